@@ -21,13 +21,17 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
-	"github.com/pygmystack/pygmy/external/docker/setup"
+	"io"
+	"os"
 
 	aur "github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
 
 	"github.com/pygmystack/pygmy/external/docker/commands"
+	"github.com/pygmystack/pygmy/external/docker/setup"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
 	"github.com/pygmystack/pygmy/internal/utils/color"
@@ -36,7 +40,7 @@ import (
 // addkeyCmd is the SSH key add command.
 var addkeyCmd = &cobra.Command{
 	Use:     "addkey",
-	Example: "pygmy addkey --key ~/.ssh/id_rsa",
+	Example: "pygmy addkey --key ~/.ssh/id_rsa --lifetime 8h",
 	Short:   "Add/re-add an SSH key to the agent",
 	Long:    `Add or re-add an SSH key to Pygmy's SSH Agent by specifying the path to the private key.`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -61,8 +65,31 @@ var addkeyCmd = &cobra.Command{
 			}
 		}
 
+		// The flags override the options of the configured keys.
+		for i := range Keys {
+			if cmd.Flags().Changed("lifetime") {
+				Keys[i].Lifetime, _ = cmd.Flags().GetString("lifetime")
+			}
+			if cmd.Flags().Changed("confirm") {
+				Keys[i].Confirm, _ = cmd.Flags().GetBool("confirm")
+			}
+			if cmd.Flags().Changed("comment") {
+				Keys[i].Comment, _ = cmd.Flags().GetString("comment")
+			}
+		}
+
+		if fromStdin, _ := cmd.Flags().GetBool("passphrase-stdin"); fromStdin {
+			passphrase, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				color.Print(aur.Red(fmt.Sprintf("could not read the passphrase: %v\n", err)))
+				os.Exit(1)
+			}
+			c.Passphrase = bytes.TrimRight(passphrase, "\r\n")
+			defer clear(c.Passphrase)
+		}
+
 		for _, k := range Keys {
-			if e := commands.SshKeyAdd(c, k.Path); e != nil {
+			if e := commands.SshKeyAdd(c, k); e != nil {
 				color.Print(aur.Red(fmt.Sprintf("%v\n", e)))
 			}
 		}
@@ -70,10 +97,10 @@ var addkeyCmd = &cobra.Command{
 		for _, s := range c.SortedServices {
 			service := c.Services[s]
 			purpose, _ := service.GetFieldString(ctx, cli, "purpose")
-			if purpose == "sshagent" {
+			if purpose == "sshagent" && !c.Agent.Forwarded() {
 				name, _ := service.GetFieldString(ctx, cli, "name")
-				d, _ := containers.Exec(ctx, cli, name, "ssh-add -l")
-				fmt.Println(string(d))
+				r, _ := containers.ExecCommand(ctx, cli, name, []string{"ssh-add", "-l"})
+				fmt.Println(string(r.Stdout))
 			}
 		}
 
@@ -83,4 +110,8 @@ var addkeyCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(addkeyCmd)
 	addkeyCmd.Flags().StringP("key", "k", "", "Path of SSH key to add")
+	addkeyCmd.Flags().StringP("lifetime", "t", "", "How long the agent keeps the key, such as 8h")
+	addkeyCmd.Flags().BoolP("confirm", "c", false, "Ask for confirmation each time the key is used")
+	addkeyCmd.Flags().StringP("comment", "", "", "Replace the comment of the key in the agent")
+	addkeyCmd.Flags().BoolP("passphrase-stdin", "", false, "Read the passphrase of the key from stdin")
 }
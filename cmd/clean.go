@@ -27,7 +27,7 @@ import (
 	"github.com/pygmystack/pygmy/external/docker/commands"
 )
 
-// stopCmd represents the stop command
+// cleanCmd represents the clean command
 var cleanCmd = &cobra.Command{
 	Use:     "clean",
 	Example: "pygmy clean",
@@ -35,10 +35,22 @@ var cleanCmd = &cobra.Command{
 	Long: `Useful for debugging or system cleaning, this command will
 remove all pygmy containers but leave the images in-tact.
 
-This command does not check if the containers are running
-because other checks do for speed convenience.`,
+Only resources created by this installation of pygmy are removed.
+Networks which are still used by other containers are kept unless
+--force is given, and volumes are kept unless --volumes is given.
+--keep-volumes keeps them explicitly, such as in scripts.
+The resources to be removed are listed for confirmation first, which
+requires a terminal unless --yes is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
+		c.DryRun, _ = cmd.Flags().GetBool("dry-run")
+		c.Force, _ = cmd.Flags().GetBool("force")
+		c.RemoveVolumes, _ = cmd.Flags().GetBool("volumes")
+		if keep, _ := cmd.Flags().GetBool("keep-volumes"); keep {
+			c.RemoveVolumes = false
+		}
+		c.AssumeYes, _ = cmd.Flags().GetBool("yes")
+
 		err := commands.Clean(c)
 		if err != nil {
 			fmt.Println(err)
@@ -50,5 +62,11 @@ because other checks do for speed convenience.`,
 func init() {
 
 	rootCmd.AddCommand(cleanCmd)
+	cleanCmd.Flags().BoolP("dry-run", "", false, "Print the actions which would be performed without performing them")
+	cleanCmd.Flags().BoolP("force", "", false, "Remove networks even if containers pygmy did not create are connected")
+	cleanCmd.Flags().BoolP("volumes", "", false, "Remove the volumes pygmy created")
+	cleanCmd.Flags().BoolP("keep-volumes", "", false, "Keep the volumes pygmy created, which is the default")
+	cleanCmd.MarkFlagsMutuallyExclusive("volumes", "keep-volumes")
+	cleanCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
 
 }
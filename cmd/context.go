@@ -0,0 +1,55 @@
+// Copyright © 2019 Karl Hepworth <Karl.Hepworth@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/pygmystack/pygmy/external/docker/commands"
+)
+
+// contextCmd represents the context command
+var contextCmd = &cobra.Command{
+	Use:     "context",
+	Example: "pygmy --context remote context",
+	Short:   "Show which Docker daemon pygmy talks to",
+	Long: `Show the Docker context pygmy uses, what selected it and whether its
+daemon can be reached. Contexts are chosen like the Docker CLI does: the
+--context flag, then DOCKER_HOST, then DOCKER_CONTEXT, then the current
+context of the Docker CLI configuration.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if jsonOutput {
+			c.JSONFormat = true
+		}
+
+		exitOnError(commands.Context(c))
+
+	},
+}
+
+func init() {
+
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Output the context in JSON format")
+
+}
@@ -37,6 +37,8 @@ then if they are, it will attempt to remove any
 services which are not running.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
+		c.DryRun, _ = cmd.Flags().GetBool("dry-run")
+
 		err := commands.Down(c)
 		if err != nil {
 			fmt.Println(err)
@@ -48,5 +50,6 @@ services which are not running.`,
 func init() {
 
 	rootCmd.AddCommand(downCmd)
+	downCmd.Flags().BoolP("dry-run", "", false, "Print the actions which would be performed without performing them")
 
 }
@@ -0,0 +1,111 @@
+// Copyright © 2019 Karl Hepworth <Karl.Hepworth@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pygmystack/pygmy/external/docker/commands"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:     "exec <service> -- <command> [args...]",
+	Example: "pygmy exec haproxy -- cat /app/haproxy.cfg",
+	Short:   "Run a command in a pygmy service",
+	Long: `Run a command in a running pygmy service, such as haproxy or
+dnsmasq, without needing to know its container name. The arguments after
+-- are passed to the command as they are. A TTY is allocated when pygmy
+runs in a terminal, and pygmy exits with the exit status of the command.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		runExec(cmd, args[0], args[1:])
+
+	},
+}
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+	Use:     "shell <service>",
+	Example: "pygmy shell haproxy",
+	Short:   "Start a shell in a pygmy service",
+	Long: `Start an interactive shell in a running pygmy service. bash is
+used when the image has it, and sh otherwise.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		var command []string
+		if shell, _ := cmd.Flags().GetString("shell"); shell != "" {
+			command = []string{shell}
+		}
+		runExec(cmd, args[0], command)
+
+	},
+}
+
+// runExec will run command in service and exit with its exit status.
+func runExec(cmd *cobra.Command, service string, command []string) {
+	env, _ := cmd.Flags().GetStringArray("env")
+	for i, v := range env {
+		// Like docker, a variable without a value is taken from the host.
+		if !strings.Contains(v, "=") {
+			env[i] = fmt.Sprintf("%s=%s", v, os.Getenv(v))
+		}
+	}
+	user, _ := cmd.Flags().GetString("user")
+	workdir, _ := cmd.Flags().GetString("workdir")
+	noTTY, _ := cmd.Flags().GetBool("no-tty")
+
+	err := commands.Exec(c, commands.ExecOptions{
+		Service:    service,
+		Cmd:        command,
+		Env:        env,
+		User:       user,
+		WorkingDir: workdir,
+		NoTTY:      noTTY,
+	})
+
+	var exit *containers.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		fmt.Println(err)
+	}
+	os.Exit(containers.ExitCode(err))
+}
+
+func init() {
+
+	rootCmd.AddCommand(execCmd, shellCmd)
+	for _, command := range []*cobra.Command{execCmd, shellCmd} {
+		command.Flags().StringArrayP("env", "e", nil, "Set an environment variable, as KEY=VALUE")
+		command.Flags().StringP("user", "u", "", "Run as another user")
+		command.Flags().StringP("workdir", "w", "", "Run in another directory")
+		command.Flags().BoolP("no-tty", "T", false, "Do not allocate a TTY")
+	}
+	shellCmd.Flags().StringP("shell", "", "", "The shell to start instead of bash or sh")
+
+}
@@ -0,0 +1,75 @@
+// Copyright © 2019 Karl Hepworth <Karl.Hepworth@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/pygmystack/pygmy/external/docker/commands"
+)
+
+// imagesCmd represents the images command
+var imagesCmd = &cobra.Command{
+	Use:     "images",
+	Example: "pygmy images save pygmy-images.tar.gz",
+	Short:   "Export and import the images pygmy uses",
+	Long: `Save the images of all configured pygmy services to an archive,
+and load them on another machine, so that pygmy can be started without
+network access.`,
+}
+
+// imagesSaveCmd represents the images save command
+var imagesSaveCmd = &cobra.Command{
+	Use:     "save <file>",
+	Example: "pygmy images save pygmy-images.tar.gz",
+	Short:   "Write the images of the pygmy services to a tar archive",
+	Long: `Write the images of all configured pygmy services to a tar
+archive, as docker save does. The images must have been pulled, and the
+archive is compressed with gzip when the file name ends in .gz or .tgz.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		exitOnError(commands.ImagesSave(c, args[0]))
+
+	},
+}
+
+// imagesLoadCmd represents the images load command
+var imagesLoadCmd = &cobra.Command{
+	Use:     "load <file>",
+	Example: "pygmy images load pygmy-images.tar.gz",
+	Short:   "Load the images of the pygmy services from a tar archive",
+	Long: `Load the images from an archive created by pygmy images save, as
+docker load does, and report any configured image it did not contain.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		exitOnError(commands.ImagesLoad(c, args[0]))
+
+	},
+}
+
+func init() {
+
+	rootCmd.AddCommand(imagesCmd)
+	imagesCmd.AddCommand(imagesSaveCmd, imagesLoadCmd)
+
+}
@@ -0,0 +1,93 @@
+// Copyright © 2019 Karl Hepworth <Karl.Hepworth@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/pygmystack/pygmy/external/docker/commands"
+)
+
+// keyCmd represents the key command
+var keyCmd = &cobra.Command{
+	Use:     "key",
+	Example: "pygmy key ls",
+	Short:   "Manage the keys in the SSH agent",
+	Long: `List and remove the SSH keys loaded in Pygmy's SSH agent,
+without restarting it. Use pygmy addkey to add a key.`,
+}
+
+// keyListCmd represents the key ls command
+var keyListCmd = &cobra.Command{
+	Use:     "ls",
+	Example: "pygmy key ls --json",
+	Short:   "List the keys in the SSH agent",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if jsonOutput {
+			c.JSONFormat = true
+		}
+
+		exitOnError(commands.KeyList(c))
+
+	},
+}
+
+// keyRemoveCmd represents the key rm command
+var keyRemoveCmd = &cobra.Command{
+	Use:     "rm <path|fingerprint>",
+	Example: "pygmy key rm ~/.ssh/id_rsa",
+	Short:   "Remove a key from the SSH agent",
+	Long: `Remove a key from the SSH agent, identified by the path of the
+private or public key, or by its SHA256 fingerprint as listed by
+pygmy key ls.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		exitOnError(commands.KeyRemove(c, args[0]))
+
+	},
+}
+
+// keyPurgeCmd represents the key purge command
+var keyPurgeCmd = &cobra.Command{
+	Use:     "purge",
+	Example: "pygmy key purge --yes",
+	Short:   "Remove every key from the SSH agent",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		c.AssumeYes, _ = cmd.Flags().GetBool("yes")
+
+		exitOnError(commands.KeyPurge(c))
+
+	},
+}
+
+func init() {
+
+	rootCmd.AddCommand(keyCmd)
+	keyCmd.AddCommand(keyListCmd, keyRemoveCmd, keyPurgeCmd)
+	keyListCmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Output the keys in JSON format")
+	keyPurgeCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+
+}
@@ -0,0 +1,67 @@
+// Copyright © 2019 Karl Hepworth <Karl.Hepworth@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pygmystack/pygmy/external/docker/commands"
+)
+
+// metricsCmd represents the metrics command
+var metricsCmd = &cobra.Command{
+	Use:     "metrics",
+	Example: "pygmy metrics serve",
+	Short:   "Expose the health of pygmy as metrics",
+}
+
+// metricsServeCmd represents the metrics serve command
+var metricsServeCmd = &cobra.Command{
+	Use:     "serve",
+	Example: "pygmy metrics serve --listen 127.0.0.1:9465",
+	Short:   "Serve an OpenMetrics endpoint for Prometheus",
+	Long: `Serve the health of pygmy at /metrics in the OpenMetrics format,
+including whether each service is running and how often it restarted,
+the status code and latency of each route, the resolver state, port
+conflicts and the number of seconds until the TLS certificate expires.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		listen, _ := cmd.Flags().GetString("listen")
+
+		if err := commands.MetricsServe(c, listen); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+	},
+}
+
+func init() {
+
+	rootCmd.AddCommand(metricsCmd)
+	metricsCmd.AddCommand(metricsServeCmd)
+	metricsServeCmd.Flags().StringP("listen", "", "127.0.0.1:9465", "Address to serve the metrics on")
+
+}
@@ -0,0 +1,74 @@
+// Copyright © 2019 Karl Hepworth <Karl.Hepworth@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pygmystack/pygmy/external/docker/commands"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:       "plan [up|down|clean|update]",
+	Example:   "pygmy plan clean --json",
+	Short:     "Show the actions a command would perform",
+	ValidArgs: []string{"up", "down", "clean", "update"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Long: `Report every action a mutating command would perform, such as
+pulling images, creating or removing containers and networks, and
+writing resolver files with sudo, without performing any of them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if jsonOutput {
+			c.JSONFormat = true
+		}
+
+		p, err := commands.Plan(c, args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if c.JSONFormat {
+			data, err := p.JSON()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			return
+		}
+
+		p.Print(os.Stdout)
+
+	},
+}
+
+func init() {
+
+	rootCmd.AddCommand(planCmd)
+	planCmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Output the plan in JSON format")
+
+}
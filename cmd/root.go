@@ -30,12 +30,15 @@ import (
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	dockercontext "github.com/pygmystack/pygmy/internal/runtime/docker/internals/context"
 )
 
 var (
-	cfgFile   string
-	c         setup.Config
-	validArgs = []string{"addkey", "clean", "down", "export", "pull", "restart", "status", "up", "update", "version"}
+	cfgFile       string
+	dockerContext string
+	c             setup.Config
+	validArgs     = []string{"addkey", "clean", "context", "down", "exec", "export", "images", "key", "metrics", "plan", "pull", "restart", "shell", "status", "up", "update", "version", "volume"}
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -67,6 +70,7 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", findConfig(), "")
+	rootCmd.PersistentFlags().StringVar(&dockerContext, "context", "", "Docker context to use, overriding DOCKER_HOST and DOCKER_CONTEXT")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -126,6 +130,8 @@ func findConfig() string {
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 
+	dockercontext.Use(dockerContext)
+
 	if cfgFile == "" {
 		viper.SetConfigFile(findConfig())
 	} else {
@@ -134,7 +140,7 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		if os.Args[1] != "completion" && !jsonOutput {
+		if os.Args[1] != "completion" && os.Args[1] != "exec" && os.Args[1] != "shell" && !jsonOutput {
 			fmt.Println("Using config file:", viper.ConfigFileUsed())
 		}
 	}
@@ -26,6 +26,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pygmystack/pygmy/external/docker/commands"
+	"github.com/pygmystack/pygmy/external/docker/setup"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
 )
 
@@ -40,6 +41,11 @@ var statusCmd = &cobra.Command{
 This includes the docker services, the resolver and SSH key status`,
 	Run: func(cmd *cobra.Command, args []string) {
 
+		if schema, _ := cmd.Flags().GetBool("schema"); schema {
+			fmt.Println(string(setup.StatusSchema))
+			return
+		}
+
 		if jsonOutput {
 			c.JSONFormat = true
 		}
@@ -58,5 +64,6 @@ func init() {
 
 	rootCmd.AddCommand(statusCmd)
 	statusCmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Output status in JSON format")
+	statusCmd.Flags().BoolP("schema", "", false, "Print the JSON Schema of the JSON status output")
 
 }
@@ -45,6 +45,10 @@ It includes dnsmasq, haproxy, mailhog, resolv and ssh-agent.`,
 		NoKey, _ := cmd.Flags().GetBool("no-addkey")
 		noResolv, _ := cmd.Flags().GetBool("no-resolver")
 		c.TLSCertPath, _ = cmd.Flags().GetString("tls-cert")
+		c.DryRun, _ = cmd.Flags().GetBool("dry-run")
+		if autoPorts, _ := cmd.Flags().GetBool("auto-ports"); autoPorts {
+			c.AutoPorts = true
+		}
 
 		if noResolv {
 			c.ResolversDisabled = true
@@ -79,6 +83,7 @@ It includes dnsmasq, haproxy, mailhog, resolv and ssh-agent.`,
 		err := commands.Up(c)
 		if err != nil {
 			fmt.Println(err)
+			os.Exit(1)
 		}
 	},
 }
@@ -93,4 +98,6 @@ func init() {
 	upCmd.Flags().BoolP("no-addkey", "", false, "Skip adding the SSH key")
 	upCmd.Flags().BoolP("no-resolver", "", false, "Skip adding or removing the Resolver")
 	upCmd.Flags().StringP("tls-cert", "", "", "Path to TLS certificate to use with the Pygmy haproxy")
+	upCmd.Flags().BoolP("dry-run", "", false, "Print the actions which would be performed without performing them")
+	upCmd.Flags().BoolP("auto-ports", "", false, "Remap ports which are already in use to free ports")
 }
@@ -22,6 +22,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"github.com/pygmystack/pygmy/external/docker/commands"
@@ -33,13 +35,30 @@ var updateCmd = &cobra.Command{
 	Aliases: []string{"pull"},
 	Example: "pygmy update",
 	Short:   "Pulls Docker Images and recreates the Containers",
-	Long: `Pull all images Pygmy uses, as well as any images containing
-the string 'uselagoon', which encompasses all lagoon images.`,
+	Long: `Pull the images of the pygmy services, and recreate the running
+services whose image changed. The digests in use before the update are
+recorded, so --rollback can return to them. --check reports the available
+updates without pulling anything.
+
+Images pinned to a digest in the configuration, such as
+pygmystack/haproxy@sha256:..., are only pulled when they are missing.
+The local Lagoon images (those containing the string 'uselagoon') are
+also pulled with --lagoon.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		err := commands.Update(c)
+		c.DryRun, _ = cmd.Flags().GetBool("dry-run")
+		check, _ := cmd.Flags().GetBool("check")
+		rollback, _ := cmd.Flags().GetBool("rollback")
+		lagoon, _ := cmd.Flags().GetBool("lagoon")
+
+		err := commands.Update(c, commands.UpdateOptions{
+			Check:    check,
+			Rollback: rollback,
+			Lagoon:   lagoon,
+		})
 		if err != nil {
 			fmt.Println(err)
+			os.Exit(1)
 		}
 
 	},
@@ -48,5 +67,10 @@ the string 'uselagoon', which encompasses all lagoon images.`,
 func init() {
 
 	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().BoolP("dry-run", "", false, "Print the actions which would be performed without performing them")
+	updateCmd.Flags().BoolP("check", "", false, "Report the available updates without pulling them")
+	updateCmd.Flags().BoolP("rollback", "", false, "Return the services to the images they used before the last update")
+	updateCmd.Flags().BoolP("lagoon", "", false, "Also pull the local Lagoon images")
+	updateCmd.MarkFlagsMutuallyExclusive("check", "rollback")
 
 }
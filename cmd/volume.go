@@ -0,0 +1,160 @@
+// Copyright © 2019 Karl Hepworth <Karl.Hepworth@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pygmystack/pygmy/external/docker/commands"
+)
+
+// volumeCmd represents the volume command
+var volumeCmd = &cobra.Command{
+	Use:     "volume",
+	Example: "pygmy volume ls",
+	Short:   "Manage the volumes pygmy creates",
+	Long: `List, inspect, remove, back up and restore the volumes pygmy
+creates. Only volumes in the pygmy configuration, or created by this
+installation of pygmy, can be managed.`,
+}
+
+// volumeListCmd represents the volume ls command
+var volumeListCmd = &cobra.Command{
+	Use:     "ls",
+	Example: "pygmy volume ls --json",
+	Short:   "List the volumes pygmy manages",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if jsonOutput {
+			c.JSONFormat = true
+		}
+
+		exitOnError(commands.VolumeList(c))
+
+	},
+}
+
+// volumeInspectCmd represents the volume inspect command
+var volumeInspectCmd = &cobra.Command{
+	Use:     "inspect <volume>",
+	Example: "pygmy volume inspect amazeeio-ssh-agent-keys",
+	Short:   "Show the details of a volume as JSON",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		exitOnError(commands.VolumeInspect(c, args[0]))
+
+	},
+}
+
+// volumeRemoveCmd represents the volume rm command
+var volumeRemoveCmd = &cobra.Command{
+	Use:     "rm <volume>",
+	Example: "pygmy volume rm amazeeio-ssh-agent-keys",
+	Short:   "Remove a volume which is not in use",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		exitOnError(commands.VolumeRemove(c, args[0]))
+
+	},
+}
+
+// volumePruneCmd represents the volume prune command
+var volumePruneCmd = &cobra.Command{
+	Use:     "prune",
+	Example: "pygmy volume prune --yes",
+	Short:   "Remove unused volumes which are no longer configured",
+	Long: `Remove the volumes created by this installation of pygmy which
+are no longer in the configuration and are not used by any container.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		c.AssumeYes, _ = cmd.Flags().GetBool("yes")
+
+		exitOnError(commands.VolumePrune(c))
+
+	},
+}
+
+// volumeBackupCmd represents the volume backup command
+var volumeBackupCmd = &cobra.Command{
+	Use:     "backup <volume> [file]",
+	Example: "pygmy volume backup mariadb-data mariadb-data.tar.gz",
+	Short:   "Write the content of a volume to a tar archive",
+	Long: `Write the content of a volume to a tar archive, using a
+throwaway container with the volume mounted. Any volume can be backed
+up, including the volumes of projects. The archive is written to
+<volume>.tar unless a file is given, and is compressed with gzip when
+the file name ends in .gz or .tgz.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		path := fmt.Sprintf("%s.tar", args[0])
+		if len(args) > 1 {
+			path = args[1]
+		}
+
+		exitOnError(commands.VolumeBackup(c, args[0], path))
+
+	},
+}
+
+// volumeRestoreCmd represents the volume restore command
+var volumeRestoreCmd = &cobra.Command{
+	Use:     "restore <volume> <file>",
+	Example: "pygmy volume restore mariadb-data mariadb-data.tar.gz --force",
+	Short:   "Restore the content of a volume from a tar archive",
+	Long: `Restore a volume from an archive created by pygmy volume backup.
+The volume is created if it does not exist. An existing volume, including
+the volumes of projects, is only replaced when --force is given, and never
+while a container uses it.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		c.Force, _ = cmd.Flags().GetBool("force")
+
+		exitOnError(commands.VolumeRestore(c, args[0], args[1]))
+
+	},
+}
+
+// exitOnError will print err and exit with a non-zero status.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+
+	rootCmd.AddCommand(volumeCmd)
+	volumeCmd.AddCommand(volumeListCmd, volumeInspectCmd, volumeRemoveCmd, volumePruneCmd, volumeBackupCmd, volumeRestoreCmd)
+	volumeListCmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Output the volumes in JSON format")
+	volumePruneCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	volumeRestoreCmd.Flags().BoolP("force", "", false, "Replace the content of an existing volume")
+
+}
@@ -1,22 +1,31 @@
 package commands
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
 	aur "github.com/logrusorgru/aurora"
 
 	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
 	"github.com/pygmystack/pygmy/internal/service/docker/ssh/agent"
 	"github.com/pygmystack/pygmy/internal/utils/color"
 )
 
-// SshKeyAdd will add a given key to the ssh agent.
-func SshKeyAdd(c setup.Config, key string) error {
+// SshKeyAdd will add a given key to the ssh agent, with its lifetime,
+// confirmation and comment options.
+func SshKeyAdd(c setup.Config, options setup.Key) error {
 	cli, ctx, err := internals.NewClient()
 	if err != nil {
 		return err
@@ -24,6 +33,11 @@ func SshKeyAdd(c setup.Config, key string) error {
 
 	setup.Setup(ctx, cli, &c)
 
+	if c.Agent.Forwarded() {
+		return errForwarded
+	}
+
+	key := options.Path
 	if key != "" {
 		if _, err := os.Stat(key); err != nil {
 			fmt.Printf("%v\n", err)
@@ -33,70 +47,208 @@ func SshKeyAdd(c setup.Config, key string) error {
 		return nil
 	}
 
+	lifetime, err := options.LifetimeDuration()
+	if err != nil {
+		return err
+	}
+
 	for _, Container := range c.Services {
 		purpose, _ := Container.GetFieldString(ctx, cli, "purpose")
 		if purpose == "addkeys" {
 
 			// Validate SSH Key before adding.
-			valid, err := agent.Validate(key)
-			if valid {
-				color.Print(aur.Green(fmt.Sprintf("Validation success for SSH key %v\n", key)))
+			k, err := agent.LoadKey(key)
+			if err != nil {
+				return fmt.Errorf("[ ] Validation failure for SSH key %v: %v", key, err)
+			}
+			if k.Encrypted {
+				color.Print(aur.Green(fmt.Sprintf("Validation success for protected SSH key %v\n", key)))
 			} else {
-				if err.Error() == "ssh: this private key is passphrase protected" {
-					color.Print(aur.Green(fmt.Sprintf("Validation success for protected SSH key %v\n", key)))
+				color.Print(aur.Green(fmt.Sprintf("Validation success for SSH key %v\n", key)))
+			}
+			// ssh-add is given the private key, and will find the
+			// public key and certificate next to it.
+			key = k.Path
+
+			// ssh-add can only ask for a passphrase from a terminal, so
+			// when the passphrase is given another way the key is sent to
+			// ssh-add on its input, still encrypted, and the passphrase
+			// is given to it by an askpass helper. The comment of an
+			// unencrypted key is changed the same way. A remote daemon
+			// can't mount the key, so it is always sent this way, with
+			// the passphrase asked for on this machine.
+			terminal := interactive()
+			remote := c.Remote.Enabled()
+			passphrase := c.Passphrase
+			if k.Encrypted && passphrase == nil {
+				p, ok, err := agent.Askpass(fmt.Sprintf("Enter passphrase for %v: ", key), terminal)
+				if err != nil {
+					return err
 				}
-				if err.Error() == "ssh: no key found" {
-					return fmt.Errorf("[ ] Validation failure for SSH key %v", key)
+				switch {
+				case ok:
+					passphrase = p
+					defer clear(p)
+				case !terminal:
+					return fmt.Errorf("[ ] SSH key %v is passphrase protected and there is no terminal to ask for it, use --passphrase-stdin or set SSH_ASKPASS", key)
+				case remote:
+					p, err := readPassphrase(fmt.Sprintf("Enter passphrase for %v: ", key))
+					if err != nil {
+						return err
+					}
+					passphrase = p
+					defer clear(p)
 				}
 			}
 
-			if runtime.GOOS == "windows" {
-				Container.Config.Cmd = []string{"windows-key-add", "/key"}
-				Container.HostConfig.Binds = append(Container.HostConfig.Binds, fmt.Sprintf("%v:/key", key))
-			} else {
-				Container.Config.Cmd = []string{"ssh-add", key}
-				Container.HostConfig.Binds = append(Container.HostConfig.Binds, fmt.Sprintf("%v:%v", key, key))
+			var input []byte
+			if passphrase != nil || (options.Comment != "" && !k.Encrypted) || remote {
+				input, err = agent.StdinInput(k, passphrase, options.Comment)
+				switch {
+				case errors.Is(err, agent.ErrIncorrectPassphrase):
+					return fmt.Errorf("[ ] Incorrect passphrase for SSH key %v", key)
+				case err != nil:
+					return fmt.Errorf("[ ] SSH key %v could not be read: %v", key, err)
+				}
+				defer clear(input)
+				if options.Comment != "" && k.Encrypted {
+					color.Print(aur.Yellow(fmt.Sprintf("Warning: the comment of SSH key %v can't be changed as it is encrypted.\n", key)))
+				}
+				if k.Certificate != nil {
+					color.Print(aur.Yellow(fmt.Sprintf("Warning: the certificate of SSH key %v is not added, as the key is sent to ssh-add on its input.\n", key)))
+				}
+			} else if options.Comment != "" {
+				color.Print(aur.Yellow(fmt.Sprintf("Warning: the comment of SSH key %v can't be changed when its passphrase is entered in the container.\n", key)))
 			}
 
-			if err := Container.Create(ctx, cli); err != nil {
-				_ = Container.Remove(ctx, cli)
-				return err
+			var args []string
+			if lifetime > 0 {
+				args = append(args, "-t", strconv.Itoa(int(lifetime.Seconds())))
 			}
-			if err := Container.Start(ctx, cli); err != nil {
-				_ = Container.Remove(ctx, cli)
-				return err
+			if options.Confirm {
+				args = append(args, "-c")
 			}
 
-			interactive, _ := Container.GetFieldBool(ctx, cli, "interactive")
-			name, _ := Container.GetFieldString(ctx, cli, "name")
-			if !interactive {
-				l, _ := containers.Logs(ctx, cli, name)
-				handled := false
-				// We need tighter control on the output of this container...
-				for _, line := range strings.Split(string(l), "\n") {
-					if strings.Contains(line, "Identity added:") {
-						handled = true
-						color.Print(aur.Green(fmt.Sprintf("Successfully added SSH key %v to agent\n", key)))
-					}
-					if strings.Contains(line, "Enter passphrase for") {
-						handled = true
-						color.Print(aur.Yellow("Warning: Passphrase protected SSH keys can only be added in interactive mode, the key will not be added.\n"))
-					}
+			if input != nil {
+				if err := addFromStdin(ctx, cli, Container, args, input); err != nil {
+					return fmt.Errorf("[ ] %v, SSH key %v was not added", err, key)
 				}
+				color.Print(aur.Green(fmt.Sprintf("Successfully added SSH key %v to agent\n", key)))
+			} else if err := addFromFile(ctx, cli, Container, args, k); err != nil {
+				return err
+			}
 
-				// Logs didn't contain known messages, log all in case of error.
-				if !handled {
-					color.Print(aur.Red("Unknown error while adding SSH key:\n"))
-					for _, line := range strings.Split(string(l), "\n") {
-						fmt.Println(line)
-					}
-				}
+			var expires time.Time
+			if lifetime > 0 {
+				expires = time.Now().Add(lifetime)
 			}
+			recordKey(ctx, cli, &c, key, expires)
+
+		}
 
-			_ = Container.Remove(ctx, cli)
+	}
+	return nil
+}
 
+// addFromStdin will add the key given by agent.StdinInput to the agent, by
+// running ssh-add in the container with the input attached.
+func addFromStdin(ctx context.Context, cli *client.Client, Container docker.Service, args []string, input []byte) error {
+	Container.Config.Cmd = agent.StdinCommand(args...)
+	Container.Config.Tty = false
+	Container.Config.OpenStdin = true
+	Container.Config.StdinOnce = true
+
+	name, _ := Container.GetFieldString(ctx, cli, "name")
+	_ = containers.Remove(ctx, cli, name)
+	if err := Container.Create(ctx, cli); err != nil {
+		_ = Container.Remove(ctx, cli)
+		return err
+	}
+	defer func() { _ = Container.Remove(ctx, cli) }()
+
+	var output bytes.Buffer
+	err := containers.RunAttached(ctx, cli, name, containers.Streams{In: bytes.NewReader(input), Out: &output, Err: &output})
+	var exit *containers.ExitError
+	if errors.As(err, &exit) {
+		return fmt.Errorf("ssh-add %v: %s", exit, strings.TrimSpace(output.String()))
+	}
+	return err
+}
+
+// addFromFile will add the key to the agent by mounting it into the
+// container, so ssh-add finds its certificate and can ask for its
+// passphrase on the terminal.
+func addFromFile(ctx context.Context, cli *client.Client, Container docker.Service, args []string, k agent.Key) error {
+	key := k.Path
+
+	// The terminal is only attached when ssh-add needs to ask for the
+	// passphrase.
+	if !k.Encrypted {
+		labels := make(map[string]string, len(Container.Config.Labels))
+		for label, value := range Container.Config.Labels {
+			labels[label] = value
+		}
+		labels["pygmy.interactive"] = "false"
+		Container.Config.Labels = labels
+	}
+
+	if runtime.GOOS == "windows" {
+		// windows-key-add only accepts the key.
+		if len(args) > 0 {
+			color.Print(aur.Yellow("Warning: the lifetime and confirmation of SSH keys are not supported on Windows.\n"))
+		}
+		Container.Config.Cmd = []string{"windows-key-add", "/key"}
+		Container.HostConfig.Binds = append(Container.HostConfig.Binds, fmt.Sprintf("%v:/key", key))
+	} else {
+		Container.Config.Cmd = append([]string{"ssh-add"}, append(args, key)...)
+		Container.HostConfig.Binds = append(Container.HostConfig.Binds, fmt.Sprintf("%v:%v", key, key))
+		if k.Certificate != nil {
+			cert := key + "-cert.pub"
+			Container.HostConfig.Binds = append(Container.HostConfig.Binds, fmt.Sprintf("%v:%v:ro", cert, cert))
+		}
+	}
+
+	if err := Container.Create(ctx, cli); err != nil {
+		_ = Container.Remove(ctx, cli)
+		return err
+	}
+	if err := Container.Start(ctx, cli); err != nil {
+		_ = Container.Remove(ctx, cli)
+		var exit *containers.ExitError
+		if errors.As(err, &exit) {
+			return fmt.Errorf("[ ] ssh-add %v, SSH key %v was not added", exit, key)
+		}
+		return err
+	}
+	defer func() { _ = Container.Remove(ctx, cli) }()
+
+	attached, _ := Container.GetFieldBool(ctx, cli, "interactive")
+	name, _ := Container.GetFieldString(ctx, cli, "name")
+	if !attached {
+		if err := containers.Wait(ctx, cli, name, container.WaitConditionNotRunning); err != nil {
+			return err
+		}
+		l, _ := containers.Logs(ctx, cli, name)
+		handled := false
+		// We need tighter control on the output of this container...
+		for _, line := range strings.Split(string(l), "\n") {
+			if strings.Contains(line, "Identity added:") {
+				handled = true
+				color.Print(aur.Green(fmt.Sprintf("Successfully added SSH key %v to agent\n", key)))
+			}
+			if strings.Contains(line, "Enter passphrase for") {
+				handled = true
+				color.Print(aur.Yellow("Warning: Passphrase protected SSH keys can only be added in interactive mode, the key will not be added.\n"))
+			}
 		}
 
+		// Logs didn't contain known messages, log all in case of error.
+		if !handled {
+			color.Print(aur.Red("Unknown error while adding SSH key:\n"))
+			for _, line := range strings.Split(string(l), "\n") {
+				fmt.Println(line)
+			}
+		}
 	}
 	return nil
 }
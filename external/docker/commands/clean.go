@@ -1,16 +1,24 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"runtime"
+	"sort"
 	"strings"
 
+	"github.com/docker/docker/client"
 	aur "github.com/logrusorgru/aurora"
 
 	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/networks"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/volumes"
 	"github.com/pygmystack/pygmy/internal/utils/color"
+	"github.com/pygmystack/pygmy/internal/utils/plan"
 )
 
 // Clean will forcibly kill and remove all of pygmy's containers in the daemon
@@ -21,56 +29,188 @@ func Clean(c setup.Config) error {
 	}
 
 	setup.Setup(ctx, cli, &c)
-	Containers, _ := containers.List(ctx, cli)
-	NetworksToClean := []string{}
 
+	p := PlanClean(ctx, cli, &c)
+	if c.DryRun {
+		return printPlan(c, p)
+	}
+
+	if len(p.Actions) == 0 {
+		p.Print(os.Stdout)
+		return nil
+	}
+
+	if !c.AssumeYes {
+		p.Print(os.Stdout)
+		if !interactive() {
+			return errNoTerminal
+		}
+		if !confirm("Do you want to remove these resources?") {
+			fmt.Println("Aborted, nothing has been removed.")
+			return nil
+		}
+	} else {
+		for _, warning := range p.Warnings {
+			color.Print(aur.Yellow(fmt.Sprintf("Warning: %s\n", warning)))
+		}
+	}
+
+	_ = p.Execute()
+
+	return nil
+}
+
+// owned will report if a resource was created by this installation of
+// pygmy: it either carries this installation's instance label, or it was
+// created before ownership labels existed and has the name of a resource
+// pygmy is configured to create.
+func owned(labels map[string]string, name string, instanceID string, configured map[string]bool) bool {
+	if id, ok := labels[docker.InstanceLabel]; ok {
+		return id == instanceID
+	}
+	return configured[name]
+}
+
+// PlanClean will determine the containers, networks, volumes and resolvers
+// which clean would remove. Resources belonging to other projects or other
+// installations of pygmy are never included.
+func PlanClean(ctx context.Context, cli *client.Client, c *setup.Config) plan.Plan {
+	p := plan.Plan{Command: "clean"}
+
+	configuredContainers := map[string]bool{}
+	for key, service := range c.Services {
+		configuredContainers[key] = true
+		if name, _ := service.GetFieldString(ctx, cli, "name"); name != "" {
+			configuredContainers[name] = true
+		}
+	}
+
+	configuredNetworks := map[string]bool{}
+	for _, network := range c.Networks {
+		configuredNetworks[network.Name] = true
+	}
+
+	// Containers
+	removed := map[string]bool{}
+	Containers, _ := containers.List(ctx, cli)
 	for _, Container := range Containers {
 		ContainerName := strings.Trim(Container.Names[0], "/")
-		target := false
-		if l := Container.Labels["pygmy.enable"]; l == "true" || l == "1" {
-			target = true
-		}
-		if l := Container.Labels["pygmy"]; l == "pygmy" {
-			target = true
+		ContainerID := Container.ID
+		if !owned(Container.Labels, ContainerName, c.InstanceID, configuredContainers) {
+			continue
 		}
+		removed[ContainerID] = true
 		if l := Container.Labels["pygmy.network"]; l != "" {
-			NetworksToClean = append(NetworksToClean, l)
+			configuredNetworks[l] = true
 		}
 
-		if target {
-			err := containers.Kill(ctx, cli, Container.ID)
-			if err == nil {
+		if Container.State == "running" {
+			p.Add(plan.New(plan.KillContainer, ContainerName, "", func() error {
+				if err := containers.Kill(ctx, cli, ContainerID); err != nil {
+					return err
+				}
 				color.Print(aur.Green(fmt.Sprintf("Successfully killed %s\n", ContainerName)))
+				return nil
+			}))
+		}
+		p.Add(plan.New(plan.RemoveContainer, ContainerName, "", func() error {
+			if err := containers.Remove(ctx, cli, ContainerID); err != nil {
+				return err
 			}
+			color.Print(aur.Green(fmt.Sprintf("Successfully removed %s\n", ContainerName)))
+			return nil
+		}))
+	}
+
+	// Networks
+	Networks, _ := networks.List(ctx, cli)
+	for _, network := range Networks {
+		name := network.Name
+		if !owned(network.Labels, name, c.InstanceID, configuredNetworks) {
+			continue
+		}
 
-			err = containers.Remove(ctx, cli, Container.ID)
-			if err == nil {
-				color.Print(aur.Green(fmt.Sprintf("Successfully removed %s\n", ContainerName)))
+		// Containers from other projects may still be using the network,
+		// removing it would disconnect them so it requires --force.
+		endpoints, _ := networks.Endpoints(ctx, cli, name)
+		var foreign []string
+		for id, endpoint := range endpoints {
+			if !removed[id] {
+				foreign = append(foreign, endpoint)
 			}
 		}
-	}
+		sort.Strings(foreign)
 
-	for _, network := range c.Networks {
-		NetworksToClean = append(NetworksToClean, network.Name)
-	}
+		detail := ""
+		if len(foreign) > 0 {
+			if !c.Force {
+				p.Warn("network %s is still used by %s and will not be removed, use --force to remove it", name, strings.Join(foreign, ", "))
+				continue
+			}
+			detail = fmt.Sprintf("disconnecting %s", strings.Join(foreign, ", "))
+		}
 
-	for n := range setup.Unique(NetworksToClean) {
-		if s, _ := networks.Status(ctx, cli, NetworksToClean[n]); s {
-			e := networks.Remove(ctx, cli, NetworksToClean[n])
-			if e != nil {
-				fmt.Println(e)
+		p.Add(plan.New(plan.RemoveNetwork, name, detail, func() error {
+			for _, endpoint := range foreign {
+				if err := networks.Disconnect(ctx, cli, name, endpoint, true); err != nil {
+					return err
+				}
 			}
-			if s, _ := networks.Status(ctx, cli, NetworksToClean[n]); !s {
-				color.Print(aur.Green(fmt.Sprintf("Successfully removed network %s\n", NetworksToClean[n])))
-			} else {
-				color.Print(aur.Red(fmt.Sprintf("Failed to remove %s\n", NetworksToClean[n])))
+			if err := networks.Remove(ctx, cli, name); err != nil {
+				return err
 			}
+			color.Print(aur.Green(fmt.Sprintf("Successfully removed network %s\n", name)))
+			return nil
+		}))
+	}
+
+	// Volumes
+	configuredVolumes := map[string]bool{}
+	for _, volume := range c.Volumes {
+		configuredVolumes[volume.Name] = true
+	}
+	Volumes, _ := volumes.List(ctx, cli)
+	var kept []string
+	for _, volume := range Volumes {
+		name := volume.Name
+		if !owned(volume.Labels, name, c.InstanceID, configuredVolumes) {
+			continue
 		}
+		if !c.RemoveVolumes {
+			kept = append(kept, name)
+			continue
+		}
+		p.Add(plan.New(plan.RemoveVolume, name, "", func() error {
+			if err := volumes.Remove(ctx, cli, name); err != nil {
+				return err
+			}
+			color.Print(aur.Green(fmt.Sprintf("Successfully removed volume %s\n", name)))
+			return nil
+		}))
+	}
+	if len(kept) > 0 {
+		p.Warn("keeping volumes %s, use --volumes to remove them", strings.Join(kept, ", "))
 	}
 
+	// Forwarded ports
+	planStopForward(c, &p)
+
+	// Resolvers
 	for _, resolver := range c.Resolvers {
-		resolver.Clean()
+		if runtime.GOOS != "windows" {
+			if _, err := os.Stat(resolver.Path()); err != nil {
+				continue
+			}
+		}
+		action := plan.New(plan.RemoveResolver, resolver.Path(), resolver.Name, func() error {
+			resolver.Clean()
+			return nil
+		})
+		if runtime.GOOS != "windows" {
+			action = action.WithSudo()
+		}
+		p.Add(action)
 	}
 
-	return nil
+	return p
 }
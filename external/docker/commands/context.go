@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/client"
+	aur "github.com/logrusorgru/aurora"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	dockercontext "github.com/pygmystack/pygmy/internal/runtime/docker/internals/context"
+	"github.com/pygmystack/pygmy/internal/utils/color"
+)
+
+// ContextStatus describes the Docker daemon pygmy talks to.
+type ContextStatus struct {
+	dockercontext.Endpoint
+	// Remote reports if the daemon runs on another machine.
+	Remote bool `json:"remote"`
+	// Version is the version of the daemon, when it can be reached.
+	Version string `json:"version,omitempty"`
+	// APIVersion is the API version negotiated with the daemon.
+	APIVersion string `json:"api_version,omitempty"`
+	// Platform is the OS and architecture of the daemon.
+	Platform string `json:"platform,omitempty"`
+	// Error is why the daemon could not be reached.
+	Error string `json:"error,omitempty"`
+}
+
+// Context will show which Docker daemon pygmy would talk to, why it was
+// chosen, and whether it can be reached.
+func Context(c setup.Config) error {
+	endpoint, err := dockercontext.Resolve()
+	if err != nil {
+		return err
+	}
+	status := ContextStatus{Endpoint: endpoint, Remote: endpoint.Remote()}
+
+	opts, err := endpoint.ClientOpts()
+	if err != nil {
+		return err
+	}
+	cli, err := client.NewClientWithOpts(append([]client.Opt{client.WithAPIVersionNegotiation()}, opts...)...)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	if status.Host == "" {
+		status.Host = cli.DaemonHost()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if version, err := cli.ServerVersion(ctx); err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Version = version.Version
+		status.APIVersion = cli.ClientVersion()
+		status.Platform = fmt.Sprintf("%s/%s", version.Os, version.Arch)
+	}
+
+	if c.JSONFormat {
+		data, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Context: %s\n", status.Context)
+	fmt.Printf("Selected by: %s\n", status.Source)
+	fmt.Printf("Host: %s\n", status.Host)
+	if status.TLS != nil {
+		fmt.Printf("TLS CA: %s\n", valueOrNone(status.TLS.CA))
+		fmt.Printf("TLS certificate: %s\n", valueOrNone(status.TLS.Cert))
+		fmt.Printf("TLS key: %s\n", valueOrNone(status.TLS.Key))
+	}
+	if status.Remote {
+		fmt.Println("The daemon runs on another machine")
+	}
+	if status.SkipTLSVerify {
+		color.Print(aur.Yellow("The certificate of the daemon is not verified\n"))
+	}
+	if status.Error != "" {
+		color.Print(aur.Red(fmt.Sprintf("The daemon could not be reached: %s\n", status.Error)))
+		return nil
+	}
+	color.Print(aur.Green(fmt.Sprintf("Docker %s (API %s) on %s\n", status.Version, status.APIVersion, status.Platform)))
+	return nil
+}
+
+// valueOrNone will display an empty value as none.
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -1,10 +1,13 @@
 package commands
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/docker/docker/client"
 
 	"github.com/pygmystack/pygmy/external/docker/setup"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/utils/plan"
 )
 
 // Down will bring pygmy down safely
@@ -15,17 +18,38 @@ func Down(c setup.Config) error {
 	}
 
 	setup.Setup(ctx, cli, &c)
-	for _, Service := range c.Services {
+
+	p := PlanDown(ctx, cli, &c)
+	if c.DryRun {
+		return printPlan(c, p)
+	}
+
+	_ = p.Execute()
+
+	return nil
+}
+
+// PlanDown will determine the actions required to stop and remove the
+// running pygmy services.
+func PlanDown(ctx context.Context, cli *client.Client, c *setup.Config) plan.Plan {
+	p := plan.Plan{Command: "down"}
+
+	for _, s := range c.SortedServices {
+		Service := c.Services[s]
 		enabled, _ := Service.GetFieldBool(ctx, cli, "enable")
 		purpose, _ := Service.GetFieldString(ctx, cli, "purpose")
 		if enabled && purpose != "addkeys" {
-			e := Service.StopAndRemove(ctx, cli)
-			if e != nil {
-				name, _ := Service.GetFieldString(ctx, cli, "name")
-				fmt.Printf("Failed to stop and remove %s\n", name)
+			name, _ := Service.GetFieldString(ctx, cli, "name")
+			if id, _ := Service.ID(ctx, cli); id == "" {
+				continue
 			}
+			p.Add(plan.New(plan.RemoveContainer, name, "stop and remove", func() error {
+				return Service.StopAndRemove(ctx, cli)
+			}))
 		}
 	}
 
-	return nil
+	planStopForward(c, &p)
+
+	return p
 }
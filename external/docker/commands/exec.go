@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"golang.org/x/term"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
+)
+
+// ExecOptions configures a command run in a pygmy service.
+type ExecOptions struct {
+	// Service is the service to run the command in.
+	Service string
+	// Cmd is the command and its arguments.
+	Cmd []string
+	// Env are extra environment variables, as KEY=VALUE.
+	Env []string
+	// User runs the command as another user.
+	User string
+	// WorkingDir is the directory the command runs in.
+	WorkingDir string
+	// NoTTY disables the TTY which is allocated when pygmy runs in a
+	// terminal.
+	NoTTY bool
+}
+
+// shellCmd starts bash when the image has it, and sh otherwise.
+var shellCmd = []string{"/bin/sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+// serviceContainer will return the container of a running pygmy service.
+// The service may be given by its name in the configuration, its
+// container name, or its name without the amazeeio- prefix. Services
+// which are disabled or not running can't be used.
+func serviceContainer(ctx context.Context, cli *client.Client, c *setup.Config, service string) (string, error) {
+	var names []string
+	for key, s := range c.Services {
+		name, _ := s.GetFieldString(ctx, cli, "name")
+		if service != key && service != name && "amazeeio-"+service != key {
+			names = append(names, key)
+			continue
+		}
+		if enabled, _ := s.GetFieldBool(ctx, cli, "enable"); !enabled {
+			return "", fmt.Errorf("service %s is disabled", service)
+		}
+		if running, _ := s.Status(ctx, cli); !running {
+			return "", fmt.Errorf("service %s is not running", service)
+		}
+		return name, nil
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("unknown service %s, expected one of %s", service, strings.Join(names, ", "))
+}
+
+// Exec will run a command in a pygmy service with the terminal attached.
+// The exit status of the command is returned as a containers.ExitError.
+func Exec(c setup.Config, options ExecOptions) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	name, err := serviceContainer(ctx, cli, &c, options.Service)
+	if err != nil {
+		return err
+	}
+
+	cmd := options.Cmd
+	if len(cmd) == 0 {
+		cmd = shellCmd
+	}
+	tty := !options.NoTTY && term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+
+	return containers.ExecAttached(ctx, cli, name, container.ExecOptions{
+		Cmd:        cmd,
+		Env:        options.Env,
+		User:       options.User,
+		WorkingDir: options.WorkingDir,
+		Tty:        tty,
+	}, containers.StandardStreams())
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+)
+
+func TestServiceContainer(t *testing.T) {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := func(name string, enabled string) docker.Service {
+		return docker.Service{Config: container.Config{Labels: map[string]string{
+			"pygmy.name":   name,
+			"pygmy.enable": enabled,
+		}}}
+	}
+	c := &setup.Config{Services: map[string]docker.Service{
+		"amazeeio-example":  service("example-pygmy-test-container", "true"),
+		"amazeeio-disabled": service("amazeeio-disabled", "false"),
+	}}
+
+	Convey("Exec: Service resolution tests...", t, func() {
+		Convey("by the name in the configuration, the container name or without the prefix", func() {
+			for _, name := range []string{"amazeeio-example", "example-pygmy-test-container", "example"} {
+				_, err := serviceContainer(ctx, cli, c, name)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "service "+name+" is not running")
+			}
+		})
+
+		Convey("of a disabled service", func() {
+			_, err := serviceContainer(ctx, cli, c, "disabled")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "service disabled is disabled")
+		})
+
+		Convey("of an unknown service", func() {
+			_, err := serviceContainer(ctx, cli, c, "unknown")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unknown service unknown, expected one of amazeeio-disabled, amazeeio-example")
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	aur "github.com/logrusorgru/aurora"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	runtimeimages "github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
+	"github.com/pygmystack/pygmy/internal/utils/color"
+)
+
+// serviceImages will return the images of the configured services, sorted
+// and without duplicates.
+func serviceImages(c *setup.Config) []string {
+	seen := map[string]bool{}
+	var images []string
+	for _, service := range c.Services {
+		image := service.Config.Image
+		if image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// ImagesSave will write the images of all configured services to a tar
+// archive at path, compressed with gzip if path ends in .gz or .tgz, so
+// that they can be loaded on a machine without network access. An
+// existing file is never overwritten.
+func ImagesSave(c setup.Config, path string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	images := serviceImages(&c)
+	var missing []string
+	for _, image := range images {
+		if !runtimeimages.Present(ctx, cli, image) {
+			missing = append(missing, image)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("[ ] the images %s have not been pulled, run pygmy update first", strings.Join(missing, ", "))
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser = file
+	if isGzip(path) {
+		w = &gzipFile{gzip.NewWriter(w), w}
+	}
+
+	err = runtimeimages.Save(ctx, cli, images, w)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	for _, image := range images {
+		fmt.Printf(" - %s\n", image)
+	}
+	color.Print(aur.Green(fmt.Sprintf("Successfully saved %d images to %s\n", len(images), path)))
+	return nil
+}
+
+// ImagesLoad will import the images in an archive created by ImagesSave,
+// and report any configured service image which is still missing.
+func ImagesLoad(c setup.Config, path string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	// The daemon detects gzip compressed archives itself.
+	if err := runtimeimages.Load(ctx, cli, file, os.Stdout); err != nil {
+		return err
+	}
+
+	for _, image := range serviceImages(&c) {
+		if !runtimeimages.Present(ctx, cli, image) {
+			color.Print(aur.Yellow(fmt.Sprintf("Warning: %s is not in %s and will be pulled by pygmy up\n", image, path)))
+		}
+	}
+	color.Print(aur.Green(fmt.Sprintf("Successfully loaded images from %s\n", path)))
+	return nil
+}
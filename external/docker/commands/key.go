@@ -0,0 +1,306 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	aur "github.com/logrusorgru/aurora"
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
+	runtimeimages "github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
+	"github.com/pygmystack/pygmy/internal/service/docker/ssh/agent"
+	"github.com/pygmystack/pygmy/internal/utils/color"
+	"github.com/pygmystack/pygmy/internal/utils/state"
+)
+
+// KeyStatus describes a key loaded in the SSH agent.
+type KeyStatus struct {
+	Fingerprint string `json:"fingerprint"`
+	Type        string `json:"type"`
+	Bits        int    `json:"bits"`
+	Comment     string `json:"comment"`
+	// Path is the key which was added, when pygmy knows it.
+	Path string `json:"path,omitempty"`
+	// Expires is when the agent will remove the key, if it was added with
+	// a lifetime.
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// agentContainer will return the name of the running SSH agent container.
+func agentContainer(ctx context.Context, cli *client.Client, c *setup.Config) (string, error) {
+	for _, s := range c.SortedServices {
+		service := c.Services[s]
+		purpose, _ := service.GetFieldString(ctx, cli, "purpose")
+		if purpose != "sshagent" {
+			continue
+		}
+		name, _ := service.GetFieldString(ctx, cli, "name")
+		if running, _ := service.Status(ctx, cli); !running {
+			return "", fmt.Errorf("the SSH agent %s is not running", name)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no SSH agent is configured")
+}
+
+// sshAdd will run ssh-add in the SSH agent container. ssh-add exits with
+// 1 when the agent has no identities, which is not treated as an error.
+func sshAdd(ctx context.Context, cli *client.Client, name string, args ...string) (string, error) {
+	result, err := containers.ExecCommand(ctx, cli, name, append([]string{"ssh-add"}, args...))
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode > 1 || (result.ExitCode == 1 && !strings.Contains(string(result.Stdout), "no identities")) {
+		return "", fmt.Errorf("ssh-add failed: %s", strings.TrimSpace(string(result.Stderr)+string(result.Stdout)))
+	}
+	return string(result.Stdout), nil
+}
+
+// agentIdentities will list the identities in the SSH agent container
+// name. In forward mode these are the host identities which can be
+// reached through the forwarded socket.
+func agentIdentities(ctx context.Context, cli *client.Client, c *setup.Config, name string) ([]agent.Identity, error) {
+	if c.Agent.Forwarded() {
+		return agent.Reachable(ctx, cli, name, runtimeimages.Mirror(agent.New().Config.Image, c.Registry.Mirror))
+	}
+	output, err := sshAdd(ctx, cli, name, "-l")
+	if err != nil {
+		return nil, err
+	}
+	return agent.ParseIdentities(output), nil
+}
+
+// errForwarded is returned when keys are managed by the host agent.
+var errForwarded = fmt.Errorf("the host SSH agent is forwarded, manage its keys with ssh-add on the host")
+
+// loadedKeys will return the keys loaded in the SSH agent, along with
+// the path they were added from when known.
+func loadedKeys(ctx context.Context, cli *client.Client, c *setup.Config, name string) ([]KeyStatus, error) {
+	identities, err := agentIdentities(ctx, cli, c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	records := keyRecords(c)
+	keys := []KeyStatus{}
+	for _, identity := range identities {
+		keys = append(keys, KeyStatus{
+			Fingerprint: identity.Fingerprint,
+			Type:        identity.Type,
+			Bits:        identity.Bits,
+			Comment:     identity.Comment,
+			Path:        records[identity.Fingerprint].Path,
+			Expires:     records[identity.Fingerprint].Expires,
+		})
+	}
+	return keys, nil
+}
+
+// keyRecords will return what pygmy knows about the keys it added, by
+// fingerprint. Configured keys are included even when they were added
+// by another tool.
+func keyRecords(c *setup.Config) map[string]state.Key {
+	records := map[string]state.Key{}
+	for _, key := range c.Keys {
+		if fingerprint, err := agent.Fingerprint(key.Path); err == nil {
+			records[fingerprint] = state.Key{Path: key.Path}
+		}
+	}
+	if s, err := state.Load(); err == nil {
+		for fingerprint, record := range s.Keys {
+			// A key which has expired may have been added again by
+			// another tool, so its expiry is no longer known.
+			if !record.Expires.IsZero() && record.Expires.Before(time.Now()) {
+				record.Expires = time.Time{}
+			}
+			records[fingerprint] = record
+		}
+	}
+	return records
+}
+
+// recordKey will remember the path a key was added from and when it
+// expires, so it can be listed and removed by path. Keys which are not
+// loaded are ignored.
+func recordKey(ctx context.Context, cli *client.Client, c *setup.Config, path string, expires time.Time) {
+	fingerprint, err := agent.Fingerprint(path)
+	if err != nil {
+		return
+	}
+	name, err := agentContainer(ctx, cli, c)
+	if err != nil {
+		return
+	}
+	keys, err := loadedKeys(ctx, cli, c, name)
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		if key.Fingerprint == fingerprint {
+			_ = state.Update(func(s *state.State) {
+				if s.Keys == nil {
+					s.Keys = map[string]state.Key{}
+				}
+				s.Keys[fingerprint] = state.Key{Path: path, Expires: expires}
+			})
+			return
+		}
+	}
+}
+
+// KeyList will list the keys loaded in the SSH agent.
+func KeyList(c setup.Config) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	name, err := agentContainer(ctx, cli, &c)
+	if err != nil {
+		return err
+	}
+	keys, err := loadedKeys(ctx, cli, &c, name)
+	if err != nil {
+		return err
+	}
+
+	if c.JSONFormat {
+		data, err := json.Marshal(keys)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(keys) == 0 {
+		fmt.Println("The SSH agent has no keys")
+		return nil
+	}
+	for _, key := range keys {
+		line := fmt.Sprintf("%s %s (%s)", key.Fingerprint, key.Comment, key.Type)
+		if key.Path != "" {
+			line += fmt.Sprintf(" from %s", key.Path)
+		}
+		if !key.Expires.IsZero() {
+			line += fmt.Sprintf(", %s", expiresIn(key.Expires))
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// KeyRemove will remove a key from the SSH agent. The key is identified
+// by its SHA256 fingerprint or by the path of the private or public key.
+func KeyRemove(c setup.Config, target string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	if c.Agent.Forwarded() {
+		return errForwarded
+	}
+
+	fingerprint := target
+	if !strings.HasPrefix(target, "SHA256:") {
+		path, _ := homedir.Expand(target)
+		if fingerprint, err = agent.Fingerprint(path); err != nil {
+			return err
+		}
+	}
+
+	name, err := agentContainer(ctx, cli, &c)
+	if err != nil {
+		return err
+	}
+	output, err := sshAdd(ctx, cli, name, "-L")
+	if err != nil {
+		return err
+	}
+
+	// A key loaded with its certificate is listed twice, and the
+	// certificate is removed along with the key.
+	var keys []agent.PublicKey
+	for _, k := range agent.ParsePublicKeys(output) {
+		if k.Matches(fingerprint) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("the key %s is not loaded in the SSH agent", target)
+	}
+
+	// ssh-add can only remove a key given a public key file, so the key is
+	// written to a temporary file in the agent container. It is passed as
+	// an argument to avoid quoting it for the shell.
+	for _, key := range keys {
+		result, err := containers.ExecCommand(ctx, cli, name, []string{
+			"sh", "-c", `f=$(mktemp) && printf '%s\n' "$1" > "$f" && ssh-add -d "$f"; rc=$?; rm -f "$f"; exit $rc`,
+			"sh", key.Key,
+		})
+		if err != nil {
+			return err
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("could not remove the key %s: %s", target, strings.TrimSpace(string(result.Stderr)))
+		}
+	}
+
+	_ = state.Update(func(s *state.State) { delete(s.Keys, fingerprint) })
+	color.Print(aur.Green(fmt.Sprintf("Successfully removed SSH key %s from agent\n", target)))
+	return nil
+}
+
+// KeyPurge will remove every key from the SSH agent.
+func KeyPurge(c setup.Config) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	if c.Agent.Forwarded() {
+		return errForwarded
+	}
+
+	name, err := agentContainer(ctx, cli, &c)
+	if err != nil {
+		return err
+	}
+
+	if !c.AssumeYes {
+		if !interactive() {
+			return errNoTerminal
+		}
+		if !confirm("Do you want to remove every key from the SSH agent?") {
+			fmt.Println("Aborted, nothing has been removed.")
+			return nil
+		}
+	}
+
+	if _, err := sshAdd(ctx, cli, name, "-D"); err != nil {
+		return err
+	}
+
+	_ = state.Update(func(s *state.State) { s.Keys = nil })
+	color.Print(aur.Green("Successfully removed all SSH keys from agent\n"))
+	return nil
+}
+
+// expiresIn will describe the remaining lifetime of a key.
+func expiresIn(expires time.Time) string {
+	return fmt.Sprintf("expires in %s", time.Until(expires).Round(time.Minute))
+}
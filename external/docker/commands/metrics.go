@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/utils/cert"
+	"github.com/pygmystack/pygmy/internal/utils/openmetrics"
+)
+
+// Metrics will return the health of pygmy as metric families, using the
+// same data which is gathered for the status command.
+func Metrics(ctx context.Context, cli *client.Client, c *setup.Config) []openmetrics.Family {
+	status := gatherStatus(ctx, cli, c, true)
+
+	up := openmetrics.Family{Name: "pygmy_service_up", Type: openmetrics.Gauge, Help: "Whether the service container is running."}
+	restarts := openmetrics.Family{Name: "pygmy_service_restarts", Type: openmetrics.Counter, Help: "Number of times Docker has restarted the service container."}
+	var names []string
+	for name := range status.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		service := status.Services[name]
+		up.Add(openmetrics.Bool(service.State), openmetrics.Labels{"service": name, "image": service.ImageRef})
+		if service.State {
+			restarts.Add(float64(service.RestartCount), openmetrics.Labels{"service": name})
+		}
+	}
+
+	routeUp := openmetrics.Family{Name: "pygmy_route_up", Type: openmetrics.Gauge, Help: "Whether the route responded with an expected status code and, for HTTPS, a valid certificate."}
+	routeStatus := openmetrics.Family{Name: "pygmy_route_status_code", Type: openmetrics.Gauge, Help: "HTTP status code of the route, zero when no response was received."}
+	routeLatency := openmetrics.Family{Name: "pygmy_route_latency_seconds", Type: openmetrics.Gauge, Help: "Time taken for the route to respond."}
+	sort.Slice(status.URLValidations, func(i, j int) bool {
+		return status.URLValidations[i].Endpoint < status.URLValidations[j].Endpoint
+	})
+	for _, route := range status.URLValidations {
+		labels := openmetrics.Labels{"url": route.Endpoint}
+		routeUp.Add(openmetrics.Bool(route.Success), labels)
+		routeStatus.Add(float64(route.StatusCode), labels)
+		routeLatency.Add(route.LatencySeconds, labels)
+	}
+
+	resolverConfigured := openmetrics.Family{Name: "pygmy_resolver_configured", Type: openmetrics.Gauge, Help: "Whether the resolver file is in place."}
+	resolverResolves := openmetrics.Family{Name: "pygmy_resolver_resolves", Type: openmetrics.Gauge, Help: "Whether the domain resolves to the local machine."}
+	for _, resolver := range status.Resolvers {
+		resolverConfigured.Add(openmetrics.Bool(resolver.Configured), openmetrics.Labels{"resolver": resolver.Name, "file": resolver.File})
+		resolverResolves.Add(openmetrics.Bool(resolver.Resolves), openmetrics.Labels{"resolver": resolver.Name})
+	}
+
+	conflicts := openmetrics.Family{Name: "pygmy_port_conflict", Type: openmetrics.Gauge, Help: "Whether a port the service needs is in use by another process."}
+	for _, check := range status.Checks {
+		if check.Kind != setup.PortCheck {
+			continue
+		}
+		conflicts.Add(openmetrics.Bool(!check.State), openmetrics.Labels{
+			"service":  check.Service,
+			"port":     check.Port,
+			"protocol": check.Protocol,
+			"host_ip":  check.HostIP,
+		})
+	}
+
+	families := []openmetrics.Family{up, restarts, routeUp, routeStatus, routeLatency, resolverConfigured, resolverResolves, conflicts}
+
+	if c.TLSCertPath != "" {
+		expiry := openmetrics.Family{Name: "pygmy_certificate_expiry_seconds", Type: openmetrics.Gauge, Help: "Seconds until the haproxy TLS certificate expires."}
+		if notAfter, err := cert.Expiry(c.TLSCertPath); err == nil {
+			expiry.Add(time.Until(notAfter).Seconds(), openmetrics.Labels{"path": c.TLSCertPath})
+		}
+		families = append(families, expiry)
+	}
+
+	return families
+}
+
+// MetricsServe will serve the metrics in the OpenMetrics format at
+// /metrics on the given address.
+func MetricsServe(c setup.Config, address string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	// Gathering the status checks ports and networks, which shouldn't
+	// happen concurrently.
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		families := Metrics(r.Context(), cli, &c)
+		mu.Unlock()
+		w.Header().Set("Content-Type", openmetrics.ContentType)
+		_ = openmetrics.Write(w, families)
+	})
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving pygmy metrics on http://%s/metrics\n", address)
+	return server.ListenAndServe()
+}
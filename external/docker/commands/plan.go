@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/utils/plan"
+)
+
+// Plan will return the actions a mutating command would perform without
+// performing any of them. Supported commands are up, down, clean and update.
+func Plan(c setup.Config, command string) (plan.Plan, error) {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return plan.Plan{}, err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	switch command {
+	case "up":
+		remaps, issues, warnings, err := preflight(ctx, cli, &c)
+		if err != nil {
+			return plan.Plan{}, err
+		}
+		p := PlanUp(ctx, cli, &c)
+		warnPreflight(&p, remaps, issues, warnings)
+		return p, nil
+	case "down":
+		return PlanDown(ctx, cli, &c), nil
+	case "clean":
+		return PlanClean(ctx, cli, &c), nil
+	case "update":
+		return PlanUpdate(ctx, cli, &c, UpdateOptions{}), nil
+	}
+
+	return plan.Plan{}, fmt.Errorf("cannot plan unknown command %q", command)
+}
+
+// printPlan will print a plan in the format requested by the configuration.
+func printPlan(c setup.Config, p plan.Plan) error {
+	if c.JSONFormat {
+		data, err := p.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	p.Print(os.Stdout)
+	return nil
+}
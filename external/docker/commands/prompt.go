@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// interactive will report if pygmy can prompt the user for input.
+func interactive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// errNoTerminal is returned when a destructive command needs confirmation
+// but there is no terminal to ask on, such as in scripts and CI.
+var errNoTerminal = errors.New("confirmation is required but there is no terminal to ask on, use --yes to proceed")
+
+// confirm will ask the user a yes/no question, defaulting to no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// readPassphrase will ask the user for a passphrase on the terminal,
+// without echoing it.
+func readPassphrase(prompt string) ([]byte, error) {
+	fmt.Print(prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	return passphrase, err
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+
+	aur "github.com/logrusorgru/aurora"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/utils/color"
+	"github.com/pygmystack/pygmy/internal/utils/plan"
+)
+
+// planForward will add forwarding the ports of a remote daemon to this
+// machine, when remote.forward is enabled and they aren't forwarded yet.
+func planForward(c *setup.Config, p *plan.Plan) {
+	if !c.Remote.Forwarded() {
+		return
+	}
+	f, err := setup.Forward(c)
+	if err != nil {
+		p.Warn("%v", err)
+		return
+	}
+	if f.Running() {
+		return
+	}
+	p.Add(plan.New(plan.StartForward, f.Host(), fmt.Sprintf("ports %v", f), func() error {
+		if err := f.Start(); err != nil {
+			return err
+		}
+		color.Print(aur.Green(fmt.Sprintf("Successfully forwarded ports %v from %v\n", f, f.Host())))
+		return nil
+	}))
+}
+
+// planStopForward will add closing the forwarded ports of a remote daemon,
+// when they are forwarded.
+func planStopForward(c *setup.Config, p *plan.Plan) {
+	if !c.Remote.Enabled() {
+		return
+	}
+	f, err := setup.Forward(c)
+	if err != nil || !f.Running() {
+		return
+	}
+	p.Add(plan.New(plan.StopForward, f.Host(), "", func() error {
+		if err := f.Stop(); err != nil {
+			return err
+		}
+		color.Print(aur.Green(fmt.Sprintf("Successfully stopped forwarding ports from %v\n", f.Host())))
+		return nil
+	}))
+}
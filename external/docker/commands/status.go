@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"os"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/client"
 	aur "github.com/logrusorgru/aurora"
@@ -16,25 +19,43 @@ import (
 	runtimecontainers "github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/networks"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/volumes"
+	"github.com/pygmystack/pygmy/internal/service/docker/ssh/agent"
 	"github.com/pygmystack/pygmy/internal/utils/color"
 	"github.com/pygmystack/pygmy/internal/utils/endpoint"
 	"github.com/pygmystack/pygmy/internal/utils/resolv"
 )
 
-// Status will show the state of all the things Pygmy manages.
-func Status(ctx context.Context, cli *client.Client, c setup.Config) {
-	setup.Setup(ctx, cli, &c)
-	checks, _ := setup.DryRun(ctx, cli, &c)
-	agentPresent := false
+// GatherStatus will collect the state of all the things Pygmy manages.
+// The configuration must already have been prepared by setup.Setup.
+func GatherStatus(ctx context.Context, cli *client.Client, c *setup.Config) setup.StatusJSON {
+	return gatherStatus(ctx, cli, c, false)
+}
 
-	if len(checks) > 0 {
-		for _, check := range checks {
-			c.JSONStatus.PortAvailability = append(c.JSONStatus.PortAvailability, check.Message)
-		}
+// gatherStatus will collect the status, leaving out what isn't reported
+// as metrics when scrape is set. The subnet and platform checks change the
+// configuration and query the registry, and listing the keys of a
+// forwarded agent starts a container, none of which must happen on every
+// scrape.
+func gatherStatus(ctx context.Context, cli *client.Client, c *setup.Config, scrape bool) setup.StatusJSON {
+	checks, _ := setup.PortChecks(ctx, cli, c)
+	if !scrape {
+		subnetChecks, _ := setup.SubnetChecks(ctx, cli, c)
+		platformChecks, _ := setup.PlatformChecks(ctx, cli, c)
+		checks = append(append(checks, subnetChecks...), platformChecks...)
 	}
+	agentPresent := false
+	status := setup.StatusJSON{}
+
+	status.SchemaVersion = setup.StatusSchemaVersion
+	status.Checks = checks
+	status.Networks = []setup.StatusJSONNetwork{}
+	status.Resolvers = []setup.StatusJSONResolver{}
+	status.Volumes = []setup.StatusJSONVolume{}
+	status.Keys = []setup.StatusJSONKey{}
+	status.URLValidations = []setup.StatusJSONURLValidation{}
 
 	// Ensure the services struct is not nil.
-	c.JSONStatus.Services = make(map[string]setup.StatusJSONStatus)
+	status.Services = make(map[string]setup.StatusJSONStatus)
 
 	Containers, _ := runtimecontainers.List(ctx, cli)
 	for _, Container := range Containers {
@@ -51,15 +72,22 @@ func Status(ctx context.Context, cli *client.Client, c setup.Config) {
 					}
 					if enabled && !discrete && name != "" {
 						if s, _ := Service.Status(ctx, cli); s {
-							c.JSONStatus.Services[name] = setup.StatusJSONStatus{
-								Container: name,
-								ImageRef:  Service.Image,
-								State:     true,
+							restarts := 0
+							if obj, err := runtimecontainers.Inspect(ctx, cli, Container.ID); err == nil {
+								restarts = obj.RestartCount
+							}
+							status.Services[name] = setup.StatusJSONStatus{
+								Container:    name,
+								ImageRef:     Service.Image,
+								ImagePresent: Service.ImagePresent(ctx, cli),
+								State:        true,
+								RestartCount: restarts,
 							}
 						} else {
-							c.JSONStatus.Services[name] = setup.StatusJSONStatus{
-								Container: name,
-								ImageRef:  Service.Image,
+							status.Services[name] = setup.StatusJSONStatus{
+								Container:    name,
+								ImageRef:     Service.Image,
+								ImagePresent: Service.ImagePresent(ctx, cli),
 							}
 						}
 					}
@@ -73,67 +101,100 @@ func Status(ctx context.Context, cli *client.Client, c setup.Config) {
 			name, _ := Service.GetFieldString(ctx, cli, "name")
 			discrete, _ := Service.GetFieldBool(ctx, cli, "discrete")
 			if !discrete {
-				c.JSONStatus.Services[name] = setup.StatusJSONStatus{
-					Container: name,
-					ImageRef:  Service.Image,
+				status.Services[name] = setup.StatusJSONStatus{
+					Container:    name,
+					ImageRef:     Service.Image,
+					ImagePresent: Service.ImagePresent(ctx, cli),
 				}
 			}
 		}
 	}
 
 	for _, Network := range c.Networks {
+		if Network.Name == "" {
+			continue
+		}
+		network := setup.StatusJSONNetwork{Name: Network.Name, Containers: []setup.StatusJSONNetworkContainer{}}
+		network.Created, _ = networks.Status(ctx, cli, Network.Name)
 		for _, Container := range Network.Containers {
-			if x, _ := networks.Connected(ctx, cli, Network.Name, Container.Name); !x {
-				c.JSONStatus.Networks = append(c.JSONStatus.Networks, fmt.Sprintf("%s is not connected to the network %s", Container.Name, Network.Name))
-			} else {
-				c.JSONStatus.Networks = append(c.JSONStatus.Networks, fmt.Sprintf("%s is connected to the network %s", Container.Name, Network.Name))
-			}
+			connected, _ := networks.Connected(ctx, cli, Network.Name, Container.Name)
+			network.Containers = append(network.Containers, setup.StatusJSONNetworkContainer{Name: Container.Name, Connected: connected})
 		}
+		status.Networks = append(status.Networks, network)
 	}
 
+	resolves := domainResolves(c.Domain, c.Remote)
 	for _, resolver := range c.Resolvers {
 		r := resolv.Resolv{Name: resolver.Name, Data: resolver.Data, Folder: resolver.Folder, File: resolver.File}
-		if s := r.Status(&docker.Params{Domain: c.Domain}); s {
-			c.JSONStatus.Resolvers = append(c.JSONStatus.Resolvers, fmt.Sprintf("Resolv %s is properly connected", resolver.Name))
-		} else {
-			c.JSONStatus.Resolvers = append(c.JSONStatus.Resolvers, fmt.Sprintf("Resolv %s is not properly connected", resolver.Name))
-		}
+		status.Resolvers = append(status.Resolvers, setup.StatusJSONResolver{
+			Name:       resolver.Name,
+			File:       r.Path(),
+			Configured: r.Status(&docker.Params{Domain: c.Domain}),
+			Resolves:   resolves,
+		})
 	}
 
 	for _, volume := range c.Volumes {
-		if s, _ := volumes.Exists(ctx, cli, volume.Name); s {
-			c.JSONStatus.Volumes = append(c.JSONStatus.Volumes, fmt.Sprintf("Volume %s has been created", volume.Name))
-		} else {
-			c.JSONStatus.Volumes = append(c.JSONStatus.Volumes, fmt.Sprintf("Volume %s has not been created", volume.Name))
+		created, _ := volumes.Exists(ctx, cli, volume.Name)
+		status.Volumes = append(status.Volumes, setup.StatusJSONVolume{Name: volume.Name, Created: created})
+	}
+
+	if c.Remote.Enabled() {
+		status.Remote = &setup.StatusJSONRemote{Host: c.Remote.Host}
+		if c.Remote.Forward {
+			if f, err := setup.Forward(c); err != nil {
+				status.Remote.Error = err.Error()
+			} else {
+				status.Remote.Ports = f.String()
+				status.Remote.Forwarded = f.Running()
+			}
 		}
 	}
 
 	// Show ssh-keys in the agent
-	if agentPresent {
-		for _, v := range c.Services {
-			purpose, _ := v.GetFieldString(ctx, cli, "purpose")
-			if purpose == "sshagent" {
-				l, _ := runtimecontainers.Exec(ctx, cli, v.Config.Labels["pygmy.name"], "ssh-add -l")
-				// Remove \u0000 & \u0001 from output messages.
-				output := strings.ReplaceAll(string(l), "\u0000", "")
-				output = strings.ReplaceAll(output, "\u0001", "")
-				output = strings.Trim(output, "\n")
-				c.JSONStatus.SSHMessages = append(c.JSONStatus.SSHMessages, output)
+	status.Agent = setup.StatusJSONAgent{Mode: setup.AgentModeContainer}
+	if c.Agent.Forwarded() {
+		status.Agent = setup.StatusJSONAgent{Mode: setup.AgentModeForward, Socket: c.Agent.Socket}
+	}
+	if agentPresent && !scrape {
+		identities, err := func() ([]agent.Identity, error) {
+			name, err := agentContainer(ctx, cli, c)
+			if err != nil {
+				return nil, err
 			}
+			return agentIdentities(ctx, cli, c, name)
+		}()
+		if err != nil {
+			status.Agent.Error = err.Error()
+		}
+		records := keyRecords(c)
+		for _, identity := range identities {
+			status.Keys = append(status.Keys, setup.StatusJSONKey{
+				Bits:        identity.Bits,
+				Fingerprint: identity.Fingerprint,
+				Type:        identity.Type,
+				Comment:     identity.Comment,
+				Path:        records[identity.Fingerprint].Path,
+				Expires:     records[identity.Fingerprint].Expires,
+			})
 		}
 	}
 
-	// List out all running projects to get their URL.
+	// List out all running projects to get their URL, along with the
+	// status codes they are expected to respond with.
 	var urls []string
+	expect := map[string]string{}
 
 	for _, Container := range c.Services {
 		Status, _ := Container.Status(ctx, cli)
 		url, _ := Container.GetFieldString(ctx, cli, "url")
 		if url != "" && Status {
 			urls = append(urls, url)
+			expect[url], _ = Container.GetFieldString(ctx, cli, "url.expect")
 		}
 	}
 
+	remaps := setup.LoadPortRemaps()
 	containers, _ := runtimecontainers.List(ctx, cli)
 	for _, container := range containers {
 		if container.State == "running" && !strings.Contains(fmt.Sprint(container.Names), "amazeeio") {
@@ -146,7 +207,9 @@ func Status(ctx context.Context, cli *client.Client, c setup.Config) {
 					if !strings.HasPrefix(url, "http") && !strings.HasPrefix(url, "https") {
 						url = "http://" + url
 					}
+					url = setup.RemapURL(url, remaps)
 					urls = append(urls, url)
+					expect[url] = container.Labels["pygmy.url.expect"]
 				}
 			}
 		}
@@ -155,19 +218,14 @@ func Status(ctx context.Context, cli *client.Client, c setup.Config) {
 	cleanurls := setup.Unique(urls)
 
 	// Validate URLs in parallel for better performance
-	type urlResult struct {
-		url    string
-		result bool
-	}
-
-	results := make(chan urlResult, len(cleanurls))
+	results := make(chan endpoint.Result, len(cleanurls))
 	var wg sync.WaitGroup
 
 	for _, url := range cleanurls {
 		wg.Add(1)
 		go func(u string) {
 			defer wg.Done()
-			results <- urlResult{u, endpoint.Validate(u)}
+			results <- endpoint.Check(u, checkOptions(c, expect[u]))
 		}(url)
 	}
 
@@ -177,12 +235,36 @@ func Status(ctx context.Context, cli *client.Client, c setup.Config) {
 	}()
 
 	for result := range results {
-		c.JSONStatus.URLValidations = append(c.JSONStatus.URLValidations, setup.StatusJSONURLValidation{
-			Endpoint: result.url,
-			Success:  result.result,
-		})
+		validation := setup.StatusJSONURLValidation{
+			Endpoint:          result.URL,
+			Success:           result.OK(),
+			StatusCode:        result.StatusCode,
+			Expected:          result.Expected,
+			Method:            result.Method,
+			LatencySeconds:    result.Latency.Seconds(),
+			Redirects:         result.Redirects,
+			CertificateIssuer: result.CertificateIssuer,
+		}
+		if result.TLSError != nil {
+			validation.TLSError = result.TLSError.Error()
+		}
+		if result.Err != nil {
+			validation.Error = result.Err.Error()
+		}
+		if !result.CertificateExpiry.IsZero() {
+			validation.CertificateExpiry = &result.CertificateExpiry
+		}
+		status.URLValidations = append(status.URLValidations, validation)
 	}
 
+	return status
+}
+
+// Status will show the state of all the things Pygmy manages.
+func Status(ctx context.Context, cli *client.Client, c setup.Config) {
+	setup.Setup(ctx, cli, &c)
+	c.JSONStatus = GatherStatus(ctx, cli, &c)
+
 	if c.JSONFormat {
 		PrintStatusJSON(c)
 		return
@@ -201,48 +283,78 @@ func PrintStatusJSON(c setup.Config) {
 
 }
 func PrintStatusHumanReadable(c setup.Config) {
-	for _, v := range c.JSONStatus.PortAvailability {
-		if strings.Contains(v, "is not able to start on port") {
-			color.Print(aur.Red(fmt.Sprintf("[ ] %s\n", v)))
-		} else {
-			color.Print(aur.Green(fmt.Sprintf("[*] %s\n", v)))
+	for _, check := range c.JSONStatus.Checks {
+		switch check.Severity {
+		case setup.SeverityError:
+			color.Print(aur.Red(fmt.Sprintf("[ ] %s\n", check.Message)))
+		case setup.SeverityWarning:
+			color.Print(aur.Yellow(fmt.Sprintf("[!] %s\n", check.Message)))
+		default:
+			color.Print(aur.Green(fmt.Sprintf("[*] %s\n", check.Message)))
 		}
 	}
 
 	for k, v := range c.JSONStatus.Services {
 		if v.State {
 			color.Print(aur.Green(fmt.Sprintf("[*] %s: Running as container %s\n", k, v.Container)))
+		} else if !v.ImagePresent {
+			color.Print(aur.Red(fmt.Sprintf("[ ] %s is not running, its image %s has not been pulled\n", k, v.ImageRef)))
 		} else {
 			color.Print(aur.Red(fmt.Sprintf("[ ] %s is not running\n", k)))
 		}
 	}
 
 	for _, v := range c.JSONStatus.Resolvers {
-		if strings.Contains(v, "not properly connected") {
-			color.Print(aur.Red(fmt.Sprintf("[ ] %s\n", v)))
+		if v.Configured {
+			color.Print(aur.Green(fmt.Sprintf("[*] Resolv %s is properly connected\n", v.Name)))
 		} else {
-			color.Print(aur.Green(fmt.Sprintf("[*] %s\n", v)))
+			color.Print(aur.Red(fmt.Sprintf("[ ] Resolv %s is not properly connected\n", v.Name)))
 		}
 	}
 
 	for _, v := range c.JSONStatus.Networks {
-		if strings.Contains(v, "is not connected to network") {
-			color.Print(aur.Red(fmt.Sprintf("[ ] %s\n", v)))
-		} else {
-			color.Print(aur.Green(fmt.Sprintf("[*] %s\n", v)))
+		for _, container := range v.Containers {
+			if container.Connected {
+				color.Print(aur.Green(fmt.Sprintf("[*] %s is connected to the network %s\n", container.Name, v.Name)))
+			} else {
+				color.Print(aur.Red(fmt.Sprintf("[ ] %s is not connected to the network %s\n", container.Name, v.Name)))
+			}
 		}
 	}
 
 	for _, v := range c.JSONStatus.Volumes {
-		if strings.Contains(v, "has not been created") {
-			color.Print(aur.Red(fmt.Sprintf("[ ] %s\n", v)))
+		if v.Created {
+			color.Print(aur.Green(fmt.Sprintf("[*] Volume %s has been created\n", v.Name)))
 		} else {
-			color.Print(aur.Green(fmt.Sprintf("[*] %s\n", v)))
+			color.Print(aur.Red(fmt.Sprintf("[ ] Volume %s has not been created\n", v.Name)))
 		}
 	}
 
-	for _, v := range c.JSONStatus.SSHMessages {
-		fmt.Printf("%s\n", v)
+	if remote := c.JSONStatus.Remote; remote != nil {
+		switch {
+		case remote.Error != "":
+			color.Print(aur.Red(fmt.Sprintf("[ ] The Docker daemon runs on %s, but its ports can't be forwarded: %s\n", remote.Host, remote.Error)))
+		case remote.Ports == "":
+			color.Print(aur.Green(fmt.Sprintf("[*] The Docker daemon runs on %s\n", remote.Host)))
+		case remote.Forwarded:
+			color.Print(aur.Green(fmt.Sprintf("[*] The Docker daemon runs on %s, ports %s are forwarded\n", remote.Host, remote.Ports)))
+		default:
+			color.Print(aur.Red(fmt.Sprintf("[ ] The Docker daemon runs on %s, ports %s are not forwarded\n", remote.Host, remote.Ports)))
+		}
+	}
+
+	if agent := c.JSONStatus.Agent; agent.Error != "" {
+		color.Print(aur.Red(fmt.Sprintf("[ ] The SSH agent keys could not be listed: %s\n", agent.Error)))
+	} else if agent.Mode == setup.AgentModeForward {
+		color.Print(aur.Green(fmt.Sprintf("[*] The host SSH agent at %s is forwarded\n", agent.Socket)))
+	}
+
+	for _, v := range c.JSONStatus.Keys {
+		if v.Expires.IsZero() {
+			fmt.Printf("%d %s %s (%s)\n", v.Bits, v.Fingerprint, v.Comment, v.Type)
+		} else {
+			fmt.Printf("%d %s %s (%s), %s\n", v.Bits, v.Fingerprint, v.Comment, v.Type, expiresIn(v.Expires))
+		}
 	}
 
 	for _, v := range c.JSONStatus.URLValidations {
@@ -254,3 +366,63 @@ func PrintStatusHumanReadable(c setup.Config) {
 	}
 
 }
+
+// domainResolves will report if the domain resolves to the local machine,
+// or to the host of a remote daemon when its ports aren't forwarded.
+func domainResolves(domain string, remote setup.Remote) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupHost(ctx, domain)
+	if err != nil {
+		return false
+	}
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		if remote.Enabled() && !remote.Forward {
+			if ip.Equal(net.ParseIP(remote.Host)) {
+				return true
+			}
+		} else if ip.IsLoopback() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOptions will return the options used to check a pygmy route. The
+// pygmy certificate is trusted, and expect is a list of status codes
+// from the pygmy.url.expect label.
+func checkOptions(c *setup.Config, expect string) endpoint.Options {
+	opts := endpoint.Options{}
+	// The routes of a remote daemon are reached on its host, whatever the
+	// domain resolves to on this machine.
+	if c.Remote.Enabled() {
+		opts.Address = c.Remote.Answer()
+	}
+	if c.TLSCertPath != "" {
+		opts.RootCertPaths = []string{c.TLSCertPath}
+	}
+	if expect != "" {
+		ranges, err := endpoint.ParseExpect(expect)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ignoring pygmy.url.expect: %v\n", err)
+		}
+		opts.Expect = ranges
+	}
+	return opts
+}
+
+// describeResult will explain why a route failed its check.
+func describeResult(r endpoint.Result) string {
+	switch {
+	case r.Err != nil:
+		return r.Err.Error()
+	case r.TLSError != nil:
+		return fmt.Sprintf("certificate is not trusted: %v", r.TLSError)
+	default:
+		return fmt.Sprintf("unexpected status code %d", r.StatusCode)
+	}
+}
@@ -1,12 +1,14 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
 	aur "github.com/logrusorgru/aurora"
 
 	"github.com/pygmystack/pygmy/external/docker/setup"
@@ -17,6 +19,8 @@ import (
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/volumes"
 	"github.com/pygmystack/pygmy/internal/utils/color"
 	"github.com/pygmystack/pygmy/internal/utils/endpoint"
+	"github.com/pygmystack/pygmy/internal/utils/plan"
+	"github.com/pygmystack/pygmy/internal/utils/state"
 )
 
 // Up will bring Pygmy up.
@@ -27,15 +31,19 @@ func Up(c setup.Config) error {
 	}
 
 	setup.Setup(ctx, cli, &c)
-	checks, _ := setup.DryRun(ctx, cli, &c)
-	agentPresent := false
 
-	foundIssues := []setup.CompatibilityCheck{}
-	for _, check := range checks {
-		if !check.State {
-			foundIssues = append(foundIssues, check)
-		}
+	remaps, foundIssues, emulated, err := preflight(ctx, cli, &c)
+	if err != nil {
+		return err
 	}
+
+	p := PlanUp(ctx, cli, &c)
+
+	if c.DryRun {
+		warnPreflight(&p, remaps, foundIssues, emulated)
+		return printPlan(c, p)
+	}
+
 	if len(foundIssues) > 0 {
 		fmt.Println("Pygmy has found the following issues:")
 		for _, issue := range foundIssues {
@@ -45,20 +53,141 @@ func Up(c setup.Config) error {
 		os.Exit(1)
 	}
 
+	for _, check := range emulated {
+		color.Print(aur.Yellow(fmt.Sprintf("Warning: %v\n", check.Message)))
+	}
+
 	if runtime.GOOS == "darwin" {
 		color.Print(aur.Cyan("Some issues are being experienced with Docker for Mac, please run `pygmy restart` if necessary.\n"))
 	}
 
-	for _, volume := range c.Volumes {
-		if s, _ := volumes.Exists(ctx, cli, volume.Name); !s {
-			_, err := volumes.Create(ctx, cli, volume)
-			if err == nil {
-				color.Print(aur.Green(fmt.Sprintf("Created volume %s\n", volume.Name)))
+	// The remaining steps still run when an action failed, so the
+	// services which did start are reported.
+	executeErr := p.Execute()
+
+	if c.AutoPorts {
+		if err := setup.SavePortRemaps(remaps); err != nil {
+			fmt.Println(err)
+		}
+		if len(remaps) > 0 {
+			color.Print(aur.Yellow("Pygmy has remapped the following ports which were already in use:\n"))
+			for _, remap := range remaps {
+				color.Print(aur.Yellow(fmt.Sprintf("  - %v: port %v is now port %v\n", remap.Service, remap.From, remap.To)))
+			}
+		}
+	}
+
+	for _, service := range c.Services {
+		name, _ := service.GetFieldString(ctx, cli, "name")
+		url, _ := service.GetFieldString(ctx, cli, "url")
+		if s, _ := service.Status(ctx, cli); s && url != "" {
+			expect, _ := service.GetFieldString(ctx, cli, "url.expect")
+			if r := endpoint.Check(url, checkOptions(&c, expect)); r.OK() {
+				fmt.Printf(" - %v (%v)\n", url, name)
 			} else {
-				fmt.Println(err)
+				fmt.Printf(" ! %v (%v): %v\n", url, name, describeResult(r))
 			}
+		}
+	}
+
+	// List out all running projects to get their URL.
+	containers, _ := runtimecontainers.List(ctx, cli)
+	var urls []string
+	expect := map[string]string{}
+	for _, container := range containers {
+		if container.State == "running" && !strings.Contains(fmt.Sprint(container.Names), "amazeeio") {
+			obj, _ := runtimecontainers.Inspect(ctx, cli, container.ID)
+			vars := obj.Config.Env
+			for _, v := range vars {
+				// Look for the environment variable $LAGOON_ROUTE.
+				if strings.Contains(v, "LAGOON_ROUTE=") {
+					url := strings.TrimPrefix(v, "LAGOON_ROUTE=")
+					if !strings.HasPrefix(url, "http") && !strings.HasPrefix(url, "https") {
+						if c.TLSCertPath != "" { // If a TLS cert is provided, we assume HTTPS.
+							url = "https://" + url
+						} else {
+							url = "http://" + url
+						}
+					}
+					if !c.Remote.Forwarded() {
+						url = setup.RemapURL(url, remaps)
+					}
+					urls = append(urls, url)
+					expect[url] = container.Labels["pygmy.url.expect"]
+				}
+			}
+		}
+	}
+
+	cleanurls := setup.Unique(urls)
+	for _, url := range cleanurls {
+		if r := endpoint.Check(url, checkOptions(&c, expect[url])); r.OK() {
+			fmt.Printf(" - %v\n", url)
 		} else {
-			color.Print(aur.Green(fmt.Sprintf("Already created volume %s\n", volume.Name)))
+			fmt.Printf(" ! %v: %v\n", url, describeResult(r))
+		}
+	}
+
+	return executeErr
+}
+
+// preflight will remap ports which are in use when auto ports are enabled,
+// and run the checks up makes before changing anything. It returns the
+// remapped ports, the checks which failed, which stop up, and the checks
+// which passed with a warning, such as services run under emulation.
+func preflight(ctx context.Context, cli *client.Client, c *setup.Config) ([]setup.PortRemap, []setup.CompatibilityCheck, []setup.CompatibilityCheck, error) {
+	var remaps []setup.PortRemap
+	if c.AutoPorts {
+		var err error
+		if remaps, err = setup.AutoPorts(ctx, cli, c); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	checks, _ := setup.PortChecks(ctx, cli, c)
+	subnetChecks, _ := setup.SubnetChecks(ctx, cli, c)
+	platformChecks, _ := setup.PlatformChecks(ctx, cli, c)
+	checks = append(checks, subnetChecks...)
+	checks = append(checks, platformChecks...)
+
+	issues := []setup.CompatibilityCheck{}
+	warnings := []setup.CompatibilityCheck{}
+	for _, check := range checks {
+		if !check.State {
+			issues = append(issues, check)
+		} else if check.Kind == setup.PlatformCheck && check.Severity == setup.SeverityWarning {
+			warnings = append(warnings, check)
+		}
+	}
+	return remaps, issues, warnings, nil
+}
+
+// warnPreflight will add the result of preflight to the plan of up.
+func warnPreflight(p *plan.Plan, remaps []setup.PortRemap, issues []setup.CompatibilityCheck, warnings []setup.CompatibilityCheck) {
+	for _, remap := range remaps {
+		p.Warn("%v would use port %v instead of port %v", remap.Service, remap.To, remap.From)
+	}
+	for _, check := range append(issues, warnings...) {
+		p.Warn("%v", check.Message)
+	}
+}
+
+// PlanUp will determine the actions required to bring Pygmy up, based on
+// the current state of the daemon and the host. The configuration is
+// expected to have been processed by setup.Setup.
+func PlanUp(ctx context.Context, cli *client.Client, c *setup.Config) plan.Plan {
+	p := plan.Plan{Command: "up"}
+	agentPresent := false
+
+	for _, volume := range c.Volumes {
+		if s, _ := volumes.Exists(ctx, cli, volume.Name); !s {
+			p.Add(plan.New(plan.CreateVolume, volume.Name, "", func() error {
+				if _, err := volumes.Create(ctx, cli, volume); err != nil {
+					return err
+				}
+				color.Print(aur.Green(fmt.Sprintf("Created volume %s\n", volume.Name)))
+				return nil
+			}))
 		}
 	}
 
@@ -74,24 +203,34 @@ func Up(c setup.Config) error {
 
 		// Do not show or add keys:
 		if enabled && purpose != "addkeys" {
-
-			if se := service.Setup(ctx, cli); se == nil {
-				fmt.Print(aur.Green(fmt.Sprintf("Successfully pulled %s\n", service.Config.Image)))
+			if !service.ImagePresent(ctx, cli) {
+				p.Add(plan.New(plan.PullImage, service.Config.Image, "", func() error {
+					if err := service.Setup(ctx, cli); err != nil {
+						return err
+					}
+					fmt.Print(aur.Green(fmt.Sprintf("Successfully pulled %s\n", service.Config.Image)))
+					return nil
+				}))
 			}
 			if status, _ := service.Status(ctx, cli); !status {
-				if ce := service.Create(ctx, cli); ce != nil {
-					// If the status is false but the container is already created, we can ignore that error.
-					if !strings.Contains(ce.Error(), "namespace is already taken") {
-						fmt.Printf("Failed to create %s: %s\n", aur.Red(name), ce)
-					}
+				if !service.Exists(ctx, cli) {
+					p.Add(plan.New(plan.CreateContainer, name, service.Config.Image, func() error {
+						if err := service.Create(ctx, cli); err != nil {
+							// If the container is already created, we can ignore that error.
+							if !strings.Contains(err.Error(), "namespace is already taken") {
+								return err
+							}
+						}
+						return nil
+					}))
 				}
-				if se := service.Start(ctx, cli); se == nil {
+				p.Add(plan.New(plan.StartContainer, name, "", func() error {
+					if err := service.Start(ctx, cli); err != nil {
+						return err
+					}
 					fmt.Print(aur.Green(fmt.Sprintf("Successfully started %s\n", name)))
-				} else {
-					fmt.Printf("Failed to start %s: %s\n", aur.Red(name), se)
-				}
-			} else {
-				fmt.Print(aur.Green(fmt.Sprintf("Already started %s\n", name)))
+					return nil
+				}))
 			}
 		}
 
@@ -104,13 +243,28 @@ func Up(c setup.Config) error {
 	// Docker network(s) creation
 	for _, Network := range c.Networks {
 		if Network.Name != "" {
-			netVal, _ := networks.Status(ctx, cli, Network.Name)
-			if !netVal {
-				if err := networks.Create(ctx, cli, &Network); err == nil {
-					color.Print(aur.Green(fmt.Sprintf("Successfully created network %s\n", Network.Name)))
-				} else {
-					color.Print(aur.Red(fmt.Sprintf("Could not create network %s\n", Network.Name)))
+			if netVal, _ := networks.Status(ctx, cli, Network.Name); !netVal {
+				detail := ""
+				if len(Network.IPAM.Config) > 0 {
+					detail = fmt.Sprintf("subnet %s", Network.IPAM.Config[0].Subnet)
 				}
+				p.Add(plan.New(plan.CreateNetwork, Network.Name, detail, func() error {
+					if err := networks.Create(ctx, cli, &Network); err != nil {
+						return err
+					}
+					if c.Subnet.Auto && len(Network.IPAM.Config) > 0 {
+						if err := state.Update(func(s *state.State) {
+							if s.Subnets == nil {
+								s.Subnets = make(map[string]string)
+							}
+							s.Subnets[Network.Name] = Network.IPAM.Config[0].Subnet
+						}); err != nil {
+							return err
+						}
+					}
+					color.Print(aur.Green(fmt.Sprintf("Successfully created network %s\n", Network.Name)))
+					return nil
+				}))
 			}
 		}
 	}
@@ -119,96 +273,63 @@ func Up(c setup.Config) error {
 	for _, s := range c.SortedServices {
 		service := c.Services[s]
 		name, nameErr := service.GetFieldString(ctx, cli, "name")
+		// Key adders never persist, so there is nothing to connect.
+		if purpose, _ := service.GetFieldString(ctx, cli, "purpose"); purpose == "addkeys" {
+			continue
+		}
 		// If the network is configured at the container level, connect it.
 		if Network, _ := service.GetFieldString(ctx, cli, "network"); Network != "" && nameErr == nil {
 			if s, _ := networks.Connected(ctx, cli, Network, name); !s {
-				if s := networks.Connect(ctx, cli, Network, name); s == nil {
-					color.Print(aur.Green(fmt.Sprintf("Successfully connected %s to %s\n", name, Network)))
-				} else {
-					discrete, _ := service.GetFieldBool(ctx, cli, "discrete")
-					if !discrete {
-						color.Print(aur.Red(fmt.Sprintf("Could not connect %s to %s\n", name, Network)))
+				discrete, _ := service.GetFieldBool(ctx, cli, "discrete")
+				p.Add(plan.New(plan.ConnectNetwork, name, fmt.Sprintf("to network %s", Network), func() error {
+					if err := networks.Connect(ctx, cli, Network, name); err != nil {
+						if discrete {
+							return nil
+						}
+						return err
 					}
-				}
-			} else {
-				color.Print(aur.Green(fmt.Sprintf("Already connected %s to %s\n", name, Network)))
+					color.Print(aur.Green(fmt.Sprintf("Successfully connected %s to %s\n", name, Network)))
+					return nil
+				}))
 			}
 		}
 	}
 
 	for _, resolver := range c.Resolvers {
-		if !resolver.Status(&docker.Params{Domain: c.Domain}) {
-			resolver.Configure(&docker.Params{Domain: c.Domain})
+		if resolver.Enabled && !resolver.Status(&docker.Params{Domain: c.Domain}) {
+			action := plan.New(plan.WriteResolver, resolver.Path(), resolver.Name, func() error {
+				resolver.Configure(&docker.Params{Domain: c.Domain})
+				return nil
+			})
+			if runtime.GOOS != "windows" {
+				action = action.WithSudo()
+			}
+			p.Add(action)
 		}
 	}
 
-	// Add ssh-keys to the agent
-	if agentPresent {
+	// Add ssh-keys to the agent, unless the host agent is forwarded.
+	if agentPresent && !c.Agent.Forwarded() {
 		for _, v := range c.Keys {
-			if e := SshKeyAdd(c, v.Path); e != nil {
-				color.Print(aur.Red(fmt.Sprintf("%v\n", e)))
-			}
+			p.Add(plan.New(plan.AddKey, v.Path, "", func() error {
+				return SshKeyAdd(*c, v)
+			}))
 		}
 	}
 
-	// ------------------------------------------------------------------------
+	// Forward the ports of a remote daemon to this machine.
+	planForward(c, &p)
+
 	// Restart the haproxy container.
 	// This is an interim fix that for some reason solves
 	// https://github.com/pygmystack/pygmy/issues/644
 	for name := range c.Services {
 		if name == "amazeeio-haproxy" {
-			if err := cli.ContainerRestart(ctx, name, container.StopOptions{}); err != nil {
-				color.Print(aur.Red(fmt.Sprintf("Failed to restart %s: %v\n", name, err)))
-			}
-		}
-	}
-
-	for _, service := range c.Services {
-		name, _ := service.GetFieldString(ctx, cli, "name")
-		url, _ := service.GetFieldString(ctx, cli, "url")
-		if s, _ := service.Status(ctx, cli); s && url != "" {
-			endpoint.Validate(url)
-			if r := endpoint.Validate(url); r {
-				fmt.Printf(" - %v (%v)\n", url, name)
-			} else {
-				fmt.Printf(" ! %v (%v)\n", url, name)
-			}
-		}
-	}
-
-	// List out all running projects to get their URL.
-	containers, _ := runtimecontainers.List(ctx, cli)
-	var urls []string
-	for _, container := range containers {
-		if container.State == "running" && !strings.Contains(fmt.Sprint(container.Names), "amazeeio") {
-			obj, _ := runtimecontainers.Inspect(ctx, cli, container.ID)
-			vars := obj.Config.Env
-			for _, v := range vars {
-				// Look for the environment variable $LAGOON_ROUTE.
-				if strings.Contains(v, "LAGOON_ROUTE=") {
-					url := strings.TrimPrefix(v, "LAGOON_ROUTE=")
-					if !strings.HasPrefix(url, "http") && !strings.HasPrefix(url, "https") {
-						if c.TLSCertPath != "" { // If a TLS cert is provided, we assume HTTPS.
-							url = "https://" + url
-						} else {
-							url = "http://" + url
-						}
-					}
-					urls = append(urls, url)
-				}
-			}
-		}
-	}
-
-	cleanurls := setup.Unique(urls)
-	for _, url := range cleanurls {
-		endpoint.Validate(url)
-		if r := endpoint.Validate(url); r {
-			fmt.Printf(" - %v\n", url)
-		} else {
-			fmt.Printf(" ! %v\n", url)
+			p.Add(plan.New(plan.RestartContainer, name, "", func() error {
+				return cli.ContainerRestart(ctx, name, container.StopOptions{})
+			}))
 		}
 	}
 
-	return nil
+	return p
 }
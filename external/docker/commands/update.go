@@ -1,16 +1,38 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	aur "github.com/logrusorgru/aurora"
 
 	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	runtimecontainers "github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
 	runtimeimages "github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/networks"
+	"github.com/pygmystack/pygmy/internal/utils/color"
+	"github.com/pygmystack/pygmy/internal/utils/plan"
+	"github.com/pygmystack/pygmy/internal/utils/state"
 )
 
+// UpdateOptions configures an update.
+type UpdateOptions struct {
+	// Check reports the available updates without pulling them.
+	Check bool
+	// Rollback returns the services to the images they used before the
+	// last update.
+	Rollback bool
+	// Lagoon also pulls the local images of Lagoon projects.
+	Lagoon bool
+}
+
 // Update will update the images for all configured services.
-func Update(c setup.Config) error {
+func Update(c setup.Config, options UpdateOptions) error {
 	cli, ctx, err := internals.NewClient()
 	if err != nil {
 		return err
@@ -19,50 +41,263 @@ func Update(c setup.Config) error {
 	// Import the configuration.
 	setup.Setup(ctx, cli, &c)
 
+	if options.Check {
+		return CheckUpdates(ctx, cli, &c)
+	}
+
+	var p plan.Plan
+	if options.Rollback {
+		p = PlanRollback(ctx, cli, &c)
+	} else {
+		p = PlanUpdate(ctx, cli, &c, options)
+	}
+	if c.DryRun {
+		return printPlan(c, p)
+	}
+
+	for _, warning := range p.Warnings {
+		color.Print(aur.Yellow(fmt.Sprintf("Warning: %s\n", warning)))
+	}
+	if options.Rollback && len(p.Actions) == 0 {
+		fmt.Println("There is no previous image to roll back to.")
+	}
+
+	return p.Execute()
+}
+
+// updatable will report if update pulls the image of a service. The images
+// of key adders are pulled with the agent.
+func updatable(ctx context.Context, cli *client.Client, service *docker.Service) bool {
+	purpose, _ := service.GetFieldString(ctx, cli, "purpose")
+	return purpose == "" || purpose == "sshagent"
+}
+
+// PlanUpdate will determine the images update would pull, and the
+// services which would be recreated if a newer image is pulled. Images
+// pinned to a digest are only pulled when they are missing.
+func PlanUpdate(ctx context.Context, cli *client.Client, c *setup.Config, options UpdateOptions) plan.Plan {
+	p := plan.Plan{Command: "update"}
+
 	// Loop over services.
-	for s := range c.Services {
+	for _, s := range c.SortedServices {
 
 		// Pull the image.
 		service := c.Services[s]
-		purpose, _ := service.GetFieldString(ctx, cli, "purpose")
-		var result string
-		var err error
-		if purpose == "" || purpose == "sshagent" {
-			result, err = runtimeimages.Pull(ctx, cli, service.Config.Image)
-			if err == nil {
-				fmt.Println(result)
-			} else {
-				fmt.Println(err)
-			}
+		name, _ := service.GetFieldString(ctx, cli, "name")
+		image := service.Config.Image
+		if !updatable(ctx, cli, &service) {
+			continue
+		}
+		if runtimeimages.Pinned(image) && service.ImagePresent(ctx, cli) {
+			continue
 		}
 
-		// If the service is running, restart it.
-		if s, _ := service.Status(ctx, cli); s && !strings.Contains(result, "is up to date") {
-			var e error
-			e = service.Stop(ctx, cli)
-			if e != nil {
-				fmt.Println(e)
+		previous, _ := runtimeimages.Digest(ctx, cli, image)
+		p.Add(plan.New(plan.PullImage, image, "", func() error {
+			result, err := runtimeimages.PullPlatform(ctx, cli, image, service.Config.Labels[runtimecontainers.PlatformLabel])
+			if err != nil {
+				return err
 			}
-			if s, _ := service.Status(ctx, cli); !s {
-				e = service.Start(ctx, cli)
-				if e != nil {
-					fmt.Println(e)
-				}
+			fmt.Println(result)
+
+			digest, err := runtimeimages.Digest(ctx, cli, image)
+			if err != nil || digest == "" {
+				return err
 			}
+			return recordImage(name, image, digest, previous)
+		}))
+
+		// If the service is running, recreate it.
+		if s, _ := service.Status(ctx, cli); s {
+			p.Add(plan.New(plan.RestartContainer, name, "recreated if a newer image was pulled", func() error {
+				if !outdated(ctx, cli, name, image) {
+					return nil
+				}
+				return recreate(ctx, cli, &service)
+			}))
 		}
 	}
 
+	if !options.Lagoon {
+		return p
+	}
+
 	images, _ := runtimeimages.List(ctx, cli)
 	for _, image := range images {
 		for _, tag := range image.RepoTags {
 			if strings.Contains(tag, "uselagoon") {
-				result, err := runtimeimages.Pull(ctx, cli, tag)
-				if err == nil {
+				p.Add(plan.New(plan.PullImage, tag, "lagoon image", func() error {
+					result, err := runtimeimages.Pull(ctx, cli, tag)
+					if err != nil {
+						return err
+					}
 					fmt.Println(result)
+					return nil
+				}))
+			}
+		}
+	}
+
+	return p
+}
+
+// PlanRollback will determine the images rollback would return the
+// services to, from the digests recorded by the last update.
+func PlanRollback(ctx context.Context, cli *client.Client, c *setup.Config) plan.Plan {
+	p := plan.Plan{Command: "update"}
+
+	s, err := state.Load()
+	if err != nil {
+		p.Warn("%v", err)
+		return p
+	}
+
+	for _, key := range c.SortedServices {
+		service := c.Services[key]
+		name, _ := service.GetFieldString(ctx, cli, "name")
+		image := service.Config.Image
+		record, ok := s.Images[name]
+		if !updatable(ctx, cli, &service) || !ok || record.Previous == "" {
+			continue
+		}
+		if runtimeimages.Pinned(image) {
+			p.Warn("%v is pinned to %v in the configuration and is not rolled back", name, image)
+			continue
+		}
+		if record.Reference != image {
+			p.Warn("%v was updated with %v but is configured with %v and is not rolled back", name, record.Reference, image)
+			continue
+		}
+
+		// The previous image is pulled by its digest if it has been
+		// removed, and tagged as the configured image so the service
+		// uses it until the next update.
+		target := runtimeimages.WithDigest(image, record.Previous)
+		p.Add(plan.New(plan.TagImage, image, fmt.Sprintf("as %v", target), func() error {
+			if _, err := cli.ImageInspect(ctx, target); err != nil {
+				result, err := runtimeimages.Pull(ctx, cli, target)
+				if err != nil {
+					return err
 				}
+				fmt.Println(result)
+			}
+			if err := runtimeimages.Tag(ctx, cli, target, image); err != nil {
+				return err
 			}
+			color.Print(aur.Green(fmt.Sprintf("Rolled back %v to %v\n", image, target)))
+			return recordImage(name, image, record.Previous, record.Digest)
+		}))
+
+		if s, _ := service.Status(ctx, cli); s {
+			p.Add(plan.New(plan.RestartContainer, name, "recreated with the previous image", func() error {
+				if !outdated(ctx, cli, name, image) {
+					return nil
+				}
+				return recreate(ctx, cli, &service)
+			}))
+		}
+	}
+
+	return p
+}
+
+// CheckUpdates will report the services with a newer image in the
+// registry, without pulling anything.
+func CheckUpdates(ctx context.Context, cli *client.Client, c *setup.Config) error {
+	for _, s := range c.SortedServices {
+		service := c.Services[s]
+		name, _ := service.GetFieldString(ctx, cli, "name")
+		image := service.Config.Image
+		if !updatable(ctx, cli, &service) {
+			continue
 		}
+
+		if runtimeimages.Pinned(image) {
+			color.Print(aur.Green(fmt.Sprintf("%v is pinned to %v\n", name, image)))
+			continue
+		}
+
+		local, _ := runtimeimages.Digest(ctx, cli, image)
+		remote, err := runtimeimages.RemoteDigest(ctx, cli, image)
+		switch {
+		case err != nil:
+			color.Print(aur.Red(fmt.Sprintf("%v could not be checked: %v\n", name, err)))
+		case local == "":
+			color.Print(aur.Yellow(fmt.Sprintf("%v has not pulled %v yet\n", name, image)))
+		case local != remote:
+			color.Print(aur.Yellow(fmt.Sprintf("%v has an update available for %v (%v -> %v)\n", name, image, shortDigest(local), shortDigest(remote))))
+		case outdated(ctx, cli, name, image):
+			color.Print(aur.Yellow(fmt.Sprintf("%v is up to date with %v but has not been recreated\n", name, image)))
+		default:
+			color.Print(aur.Green(fmt.Sprintf("%v is up to date with %v\n", name, image)))
+		}
+	}
+	return nil
+}
+
+// shortDigest will abbreviate a digest for display, like image IDs.
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
 	}
+	return digest
+}
 
+// recordImage will record the digest a service is now using in the state
+// file, and the digest it replaces when it changed.
+func recordImage(name string, image string, digest string, previous string) error {
+	return state.Update(func(s *state.State) {
+		if s.Images == nil {
+			s.Images = make(map[string]state.Image)
+		}
+		record := s.Images[name]
+		if record.Digest != digest || record.Reference != image {
+			if previous != "" && previous != digest {
+				record.Previous = previous
+			}
+			record.Updated = time.Now()
+		}
+		record.Reference = image
+		record.Digest = digest
+		s.Images[name] = record
+	})
+}
+
+// outdated will report if the container of a service was created from
+// another image than the one image now refers to.
+func outdated(ctx context.Context, cli *client.Client, name string, image string) bool {
+	container, err := runtimecontainers.Inspect(ctx, cli, name)
+	if err != nil {
+		return false
+	}
+	inspect, err := cli.ImageInspect(ctx, image)
+	if err != nil {
+		return false
+	}
+	return container.Image != inspect.ID
+}
+
+// recreate will replace the container of a service with a new one, so that
+// it uses the image its configuration now refers to.
+func recreate(ctx context.Context, cli *client.Client, service *docker.Service) error {
+	name, _ := service.GetFieldString(ctx, cli, "name")
+	if err := service.StopAndRemove(ctx, cli); err != nil {
+		return err
+	}
+	if err := service.Create(ctx, cli); err != nil {
+		return err
+	}
+	if err := service.Start(ctx, cli); err != nil {
+		return err
+	}
+	if network, _ := service.GetFieldString(ctx, cli, "network"); network != "" {
+		if connected, _ := networks.Connected(ctx, cli, network, name); !connected {
+			if err := networks.Connect(ctx, cli, network, name); err != nil {
+				return err
+			}
+		}
+	}
+	color.Print(aur.Green(fmt.Sprintf("Successfully recreated %s\n", name)))
 	return nil
 }
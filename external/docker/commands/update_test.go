@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/utils/plan"
+	"github.com/pygmystack/pygmy/internal/utils/state"
+)
+
+const (
+	digestA = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+func TestRecordImage(t *testing.T) {
+	t.Setenv("PYGMY_STATE", filepath.Join(t.TempDir(), "state.json"))
+
+	Convey("Update: Image record tests...", t, func() {
+		So(os.RemoveAll(state.Path()), ShouldBeNil)
+		So(recordImage("example", "nginx:1.27", digestA, ""), ShouldBeNil)
+		s, err := state.Load()
+		So(err, ShouldBeNil)
+		So(s.Images["example"].Digest, ShouldEqual, digestA)
+		So(s.Images["example"].Previous, ShouldBeEmpty)
+
+		Convey("the replaced digest becomes the previous one", func() {
+			So(recordImage("example", "nginx:1.27", digestB, digestA), ShouldBeNil)
+			s, err := state.Load()
+			So(err, ShouldBeNil)
+			So(s.Images["example"].Digest, ShouldEqual, digestB)
+			So(s.Images["example"].Previous, ShouldEqual, digestA)
+
+			Convey("and a rollback swaps them back", func() {
+				So(recordImage("example", "nginx:1.27", digestA, digestB), ShouldBeNil)
+				s, err := state.Load()
+				So(err, ShouldBeNil)
+				So(s.Images["example"].Digest, ShouldEqual, digestA)
+				So(s.Images["example"].Previous, ShouldEqual, digestB)
+			})
+
+			Convey("and is kept when the digest doesn't change", func() {
+				So(recordImage("example", "nginx:1.27", digestB, digestB), ShouldBeNil)
+				s, err := state.Load()
+				So(err, ShouldBeNil)
+				So(s.Images["example"].Previous, ShouldEqual, digestA)
+			})
+		})
+	})
+}
+
+func TestPlanRollback(t *testing.T) {
+	t.Setenv("PYGMY_STATE", filepath.Join(t.TempDir(), "state.json"))
+
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := func(name string, image string) docker.Service {
+		return docker.Service{Config: container.Config{
+			Image:  image,
+			Labels: map[string]string{"pygmy.name": name, "pygmy.enable": "true"},
+		}}
+	}
+	c := &setup.Config{
+		Services: map[string]docker.Service{
+			"example-updated": service("example-updated", "nginx:1.27"),
+			"example-pinned":  service("example-pinned", "nginx@"+digestB),
+			"example-changed": service("example-changed", "nginx:1.27"),
+			"example-new":     service("example-new", "nginx:1.27"),
+		},
+		SortedServices: []string{"example-updated", "example-pinned", "example-changed", "example-new"},
+	}
+
+	Convey("Update: Rollback plan tests...", t, func() {
+		So(state.Update(func(s *state.State) {
+			s.Images = map[string]state.Image{
+				"example-updated": {Reference: "nginx:1.27", Digest: digestB, Previous: digestA},
+				"example-pinned":  {Reference: "nginx:1.27", Digest: digestB, Previous: digestA},
+				"example-changed": {Reference: "nginx:1.25", Digest: digestB, Previous: digestA},
+				"example-new":     {Reference: "nginx:1.27", Digest: digestB},
+			}
+		}), ShouldBeNil)
+
+		p := PlanRollback(ctx, cli, c)
+
+		var tagged []string
+		for _, action := range p.Actions {
+			if action.Kind == plan.TagImage {
+				tagged = append(tagged, action.Target+" "+action.Detail)
+			}
+		}
+		So(tagged, ShouldResemble, []string{"nginx:1.27 as nginx@" + digestA})
+
+		So(p.Warnings, ShouldHaveLength, 2)
+		So(strings.Join(p.Warnings, "\n"), ShouldContainSubstring, "example-pinned is pinned to nginx@"+digestB)
+		So(strings.Join(p.Warnings, "\n"), ShouldContainSubstring, "example-changed was updated with nginx:1.25 but is configured with nginx:1.27")
+	})
+}
@@ -0,0 +1,444 @@
+package commands
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	aur "github.com/logrusorgru/aurora"
+
+	"github.com/pygmystack/pygmy/external/docker/setup"
+	"github.com/pygmystack/pygmy/internal/runtime/docker"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/volumes"
+	"github.com/pygmystack/pygmy/internal/utils/color"
+)
+
+// VolumeStatus describes a volume which pygmy manages.
+type VolumeStatus struct {
+	Name       string            `json:"name"`
+	Created    bool              `json:"created"`
+	Configured bool              `json:"configured"`
+	Driver     string            `json:"driver,omitempty"`
+	Mountpoint string            `json:"mountpoint,omitempty"`
+	CreatedAt  string            `json:"created_at,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	UsedBy     []string          `json:"used_by"`
+}
+
+// managedVolumes will return the status of every volume pygmy manages:
+// those in the configuration and those labelled by this installation.
+func managedVolumes(ctx context.Context, cli *client.Client, c *setup.Config) ([]VolumeStatus, error) {
+	existing, err := volumes.List(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := map[string]VolumeStatus{}
+	for _, v := range c.Volumes {
+		statuses[v.Name] = VolumeStatus{Name: v.Name, Configured: true}
+	}
+	for _, v := range existing {
+		s, configured := statuses[v.Name]
+		if !configured && v.Labels[docker.InstanceLabel] != c.InstanceID {
+			continue
+		}
+		s.Name = v.Name
+		s.Created = true
+		s.Driver = v.Driver
+		s.Mountpoint = v.Mountpoint
+		s.CreatedAt = v.CreatedAt
+		s.Labels = v.Labels
+		statuses[v.Name] = s
+	}
+
+	var result []VolumeStatus
+	for _, s := range statuses {
+		if s.Created {
+			s.UsedBy, _ = volumes.UsedBy(ctx, cli, s.Name)
+		}
+		if s.UsedBy == nil {
+			s.UsedBy = []string{}
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// managedVolume will return the status of a single volume pygmy manages.
+func managedVolume(ctx context.Context, cli *client.Client, c *setup.Config, name string) (VolumeStatus, error) {
+	all, err := managedVolumes(ctx, cli, c)
+	if err != nil {
+		return VolumeStatus{}, err
+	}
+	for _, s := range all {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return VolumeStatus{}, fmt.Errorf("volume %s is not managed by pygmy", name)
+}
+
+// anyVolume will return the status of a volume, whether pygmy manages it
+// or not, and report if it is managed. A volume which doesn't exist is
+// returned without Created set.
+func anyVolume(ctx context.Context, cli *client.Client, c *setup.Config, name string) (VolumeStatus, bool, error) {
+	if s, err := managedVolume(ctx, cli, c, name); err == nil {
+		return s, true, nil
+	}
+	exists, err := volumes.Exists(ctx, cli, name)
+	if err != nil || !exists {
+		return VolumeStatus{Name: name, UsedBy: []string{}}, false, err
+	}
+	v, err := volumes.Inspect(ctx, cli, name)
+	if err != nil {
+		return VolumeStatus{}, false, err
+	}
+	s := VolumeStatus{
+		Name:       v.Name,
+		Created:    true,
+		Driver:     v.Driver,
+		Mountpoint: v.Mountpoint,
+		CreatedAt:  v.CreatedAt,
+		Labels:     v.Labels,
+	}
+	s.UsedBy, _ = volumes.UsedBy(ctx, cli, name)
+	return s, false, nil
+}
+
+// volumeHelperImage will return the image used for the throwaway
+// containers which copy data in and out of volumes. The SSH agent image
+// is used because pygmy will already have it.
+func volumeHelperImage(c *setup.Config) string {
+	if service, ok := c.Services["amazeeio-ssh-agent"]; ok && service.Config.Image != "" {
+		return service.Config.Image
+	}
+	return "pygmystack/ssh-agent"
+}
+
+// VolumeList will list the volumes pygmy manages.
+func VolumeList(c setup.Config) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	statuses, err := managedVolumes(ctx, cli, &c)
+	if err != nil {
+		return err
+	}
+
+	if c.JSONFormat {
+		if statuses == nil {
+			statuses = []VolumeStatus{}
+		}
+		data, err := json.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No volumes are managed by pygmy")
+		return nil
+	}
+	for _, s := range statuses {
+		if !s.Created {
+			color.Print(aur.Red(fmt.Sprintf("[ ] %s has not been created\n", s.Name)))
+			continue
+		}
+		usage := "not in use"
+		if len(s.UsedBy) > 0 {
+			usage = fmt.Sprintf("used by %s", strings.Join(s.UsedBy, ", "))
+		}
+		color.Print(aur.Green(fmt.Sprintf("[*] %s (%s, %s)\n", s.Name, s.Driver, usage)))
+	}
+	return nil
+}
+
+// VolumeInspect will print the details of a volume pygmy manages as JSON.
+func VolumeInspect(c setup.Config, name string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	s, err := managedVolume(ctx, cli, &c, name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// VolumeRemove will remove a volume pygmy manages, provided no container
+// is using it.
+func VolumeRemove(c setup.Config, name string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	// Any volume can be backed up, as it is only read.
+	s, _, err := anyVolume(ctx, cli, &c, name)
+	if err != nil {
+		return err
+	}
+	if !s.Created {
+		return fmt.Errorf("volume %s has not been created", name)
+	}
+	if len(s.UsedBy) > 0 {
+		return fmt.Errorf("volume %s is in use by %s", name, strings.Join(s.UsedBy, ", "))
+	}
+
+	if err := volumes.Remove(ctx, cli, name); err != nil {
+		return err
+	}
+	color.Print(aur.Green(fmt.Sprintf("Successfully removed volume %s\n", name)))
+	return nil
+}
+
+// VolumePrune will remove the volumes created by this installation which
+// are no longer configured and are not in use.
+func VolumePrune(c setup.Config) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	statuses, err := managedVolumes(ctx, cli, &c)
+	if err != nil {
+		return err
+	}
+
+	var prune []string
+	for _, s := range statuses {
+		if s.Created && !s.Configured && len(s.UsedBy) == 0 {
+			prune = append(prune, s.Name)
+		}
+	}
+
+	if len(prune) == 0 {
+		fmt.Println("No unused volumes to remove")
+		return nil
+	}
+
+	if !c.AssumeYes {
+		fmt.Printf("The following volumes will be removed:\n  - %s\n", strings.Join(prune, "\n  - "))
+		if !interactive() {
+			return errNoTerminal
+		}
+		if !confirm("Do you want to remove these volumes?") {
+			fmt.Println("Aborted, nothing has been removed.")
+			return nil
+		}
+	}
+
+	for _, name := range prune {
+		if err := volumes.Remove(ctx, cli, name); err != nil {
+			color.Print(aur.Red(fmt.Sprintf("Failed to remove volume %s: %v\n", name, err)))
+			continue
+		}
+		color.Print(aur.Green(fmt.Sprintf("Successfully removed volume %s\n", name)))
+	}
+	return nil
+}
+
+// VolumeBackup will write a tar archive of a volume to path, compressed
+// with gzip if path ends in .gz or .tgz. An existing file is never
+// overwritten.
+func VolumeBackup(c setup.Config, name string, path string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	// Any volume can be backed up, as it is only read.
+	s, _, err := anyVolume(ctx, cli, &c, name)
+	if err != nil {
+		return err
+	}
+	if !s.Created {
+		return fmt.Errorf("volume %s has not been created", name)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser = file
+	if isGzip(path) {
+		w = &gzipFile{gzip.NewWriter(w), w}
+	}
+
+	err = volumes.Backup(ctx, cli, name, volumeHelperImage(&c), map[string]string{docker.InstanceLabel: c.InstanceID}, w)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	color.Print(aur.Green(fmt.Sprintf("Successfully backed up volume %s to %s\n", name, path)))
+	return nil
+}
+
+// VolumeRestore will restore a volume from an archive created by
+// VolumeBackup. An existing volume, managed by pygmy or not, is only
+// replaced when c.Force is set.
+func VolumeRestore(c setup.Config, name string, path string) error {
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		return err
+	}
+
+	setup.Setup(ctx, cli, &c)
+
+	// Restoring a volume which doesn't exist is allowed, it will become a
+	// volume owned by this installation. A volume pygmy doesn't manage,
+	// such as the database of a project, can be replaced with --force and
+	// stays unmanaged.
+	s, managed, err := anyVolume(ctx, cli, &c, name)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	var r io.Reader = file
+	if isGzip(path) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	labels := map[string]string{docker.InstanceLabel: c.InstanceID}
+	definition := volume.Volume{Name: name}
+	if v, ok := c.Volumes[name]; ok {
+		definition = v
+	}
+
+	if s.Created {
+		if !c.Force && !managed {
+			return fmt.Errorf("volume %s already exists and is not managed by pygmy, use --force to replace its content", name)
+		} else if !c.Force {
+			return fmt.Errorf("volume %s already exists, use --force to replace its content", name)
+		}
+		if len(s.UsedBy) > 0 {
+			return fmt.Errorf("volume %s is in use by %s", name, strings.Join(s.UsedBy, ", "))
+		}
+		// The replacement keeps the driver, options and labels of the
+		// volume it replaces.
+		original, err := volumes.Inspect(ctx, cli, name)
+		if err != nil {
+			return err
+		}
+		definition = volume.Volume{Name: name, Driver: original.Driver, Options: original.Options, Labels: original.Labels}
+	}
+
+	if managed || !s.Created {
+		definition.Labels = withLabels(definition.Labels, labels)
+	}
+
+	if !s.Created {
+		if _, err := volumes.Create(ctx, cli, definition); err != nil {
+			return err
+		}
+		if err := volumes.Restore(ctx, cli, name, volumeHelperImage(&c), labels, r); err != nil {
+			_ = volumes.Remove(ctx, cli, name)
+			return err
+		}
+		color.Print(aur.Green(fmt.Sprintf("Successfully restored volume %s from %s\n", name, path)))
+		return nil
+	}
+
+	// The archive is restored into a staging volume first, so the existing
+	// volume is only replaced once the archive has been read completely.
+	staging, err := volumes.Staging(ctx, cli, name, labels)
+	if err != nil {
+		return err
+	}
+	if err := volumes.Restore(ctx, cli, staging.Name, volumeHelperImage(&c), labels, r); err != nil {
+		_ = volumes.Remove(ctx, cli, staging.Name)
+		return fmt.Errorf("could not restore %s, volume %s has not been changed: %w", path, name, err)
+	}
+
+	if err := volumes.Remove(ctx, cli, name); err != nil {
+		_ = volumes.Remove(ctx, cli, staging.Name)
+		return err
+	}
+	if _, err := volumes.Create(ctx, cli, definition); err != nil {
+		return fmt.Errorf("could not recreate volume %s, the restored content is kept in volume %s: %w", name, staging.Name, err)
+	}
+	if err := volumes.Copy(ctx, cli, staging.Name, name, volumeHelperImage(&c), labels); err != nil {
+		return fmt.Errorf("could not copy the restored content to volume %s, it is kept in volume %s: %w", name, staging.Name, err)
+	}
+	_ = volumes.Remove(ctx, cli, staging.Name)
+
+	color.Print(aur.Green(fmt.Sprintf("Successfully restored volume %s from %s\n", name, path)))
+	return nil
+}
+
+// withLabels will return a copy of labels with extra added.
+func withLabels(labels map[string]string, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(labels)+len(extra))
+	for k, v := range labels {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// isGzip will report if a path should be gzip compressed.
+func isGzip(path string) bool {
+	return strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// gzipFile closes both the gzip stream and the underlying file.
+type gzipFile struct {
+	*gzip.Writer
+	file io.Closer
+}
+
+// Close will flush the gzip stream and close the underlying file.
+func (g *gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		_ = g.file.Close()
+		return err
+	}
+	return g.file.Close()
+}
@@ -2,8 +2,10 @@ package setup
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
@@ -13,18 +15,73 @@ import (
 	"github.com/shirou/gopsutil/process"
 )
 
+// CheckKind identifies what a CompatibilityCheck has tested.
+type CheckKind string
+
+const (
+	// PortCheck is the availability of a host port for a service.
+	PortCheck CheckKind = "port"
+	// SubnetCheck is the availability of a subnet for a network.
+	SubnetCheck CheckKind = "subnet"
+	// PlatformCheck is the availability of an image for the platform a
+	// service runs as.
+	PlatformCheck CheckKind = "platform"
+)
+
+// Severity is how a CompatibilityCheck result should be treated.
+type Severity string
+
+const (
+	// SeverityOK is a check which passed.
+	SeverityOK Severity = "ok"
+	// SeverityWarning is a check which passed but could not be verified.
+	SeverityWarning Severity = "warning"
+	// SeverityError is a check which failed and will prevent Pygmy from
+	// starting.
+	SeverityError Severity = "error"
+)
+
 // CompatibilityCheck is a struct of fields associated to reporting of
-// a result state.
+// a result state. The fields which apply depend on the Kind, and the
+// Message is a human-readable summary of the result.
 type CompatibilityCheck struct {
-	State   bool   `yaml:"value"`
-	Message string `yaml:"string"`
+	Kind      CheckKind `json:"kind" yaml:"kind"`
+	Severity  Severity  `json:"severity" yaml:"severity"`
+	Service   string    `json:"service,omitempty" yaml:"service,omitempty"`
+	Network   string    `json:"network,omitempty" yaml:"network,omitempty"`
+	Subnet    string    `json:"subnet,omitempty" yaml:"subnet,omitempty"`
+	Platform  string    `json:"platform,omitempty" yaml:"platform,omitempty"`
+	Port      string    `json:"port,omitempty" yaml:"port,omitempty"`
+	Protocol  string    `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	HostIP    string    `json:"host_ip,omitempty" yaml:"hostIP,omitempty"`
+	PID       int       `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Process   string    `json:"process,omitempty" yaml:"process,omitempty"`
+	Container string    `json:"container,omitempty" yaml:"container,omitempty"`
+	State     bool      `json:"state" yaml:"value"`
+	Message   string    `json:"message" yaml:"string"`
 }
 
-// DryRun will check for. It is here to check for port compatibility before
-// Pygmy attempts to start any containers and provide the user with a report.
+// DryRun will check for port compatibility.
+//
+// Deprecated: DryRun does not perform a dry run, use PortChecks instead.
 func DryRun(ctx context.Context, cli *client.Client, c *Config) ([]CompatibilityCheck, error) {
+	return PortChecks(ctx, cli, c)
+}
+
+// PortChecks is here to check for port compatibility before Pygmy
+// attempts to start any containers and provide the user with a report.
+// TCP and UDP bindings are checked on the address given by their HostIP,
+// and bindings on all addresses are checked on both the IPv4 and IPv6
+// wildcard addresses. The ports of a remote daemon are checked on the
+// remote host instead.
+func PortChecks(ctx context.Context, cli *client.Client, c *Config) ([]CompatibilityCheck, error) {
+
+	if c.Remote.Enabled() {
+		return remotePortChecks(ctx, cli, c), nil
+	}
 
 	messages := []CompatibilityCheck{}
+	ipv6 := ipv6Supported()
 
 	for _, Service := range c.Services {
 		name, _ := Service.GetFieldString(ctx, cli, "name")
@@ -38,43 +95,78 @@ func DryRun(ctx context.Context, cli *client.Client, c *Config) ([]Compatibility
 			continue
 		}
 
+		checked := map[string]bool{}
+
 		for PortBinding, Ports := range Service.HostConfig.PortBindings {
-			if !strings.Contains(string(PortBinding), "tcp") {
+			proto := PortBinding.Proto()
+			if proto != "tcp" && proto != "udp" {
 				continue
 			}
 
 			for _, Port := range Ports {
 				p := fmt.Sprint(Port.HostPort)
-				conn, err := net.Dial("tcp", "localhost:"+p)
-				if conn != nil {
-					if e := conn.Close(); e != nil {
-						fmt.Println(e)
-					}
+				if p == "" {
+					continue
 				}
-				if err != nil {
-					messages = append(messages, CompatibilityCheck{
-						State:   true,
-						Message: fmt.Sprintf("%v is able to start on port %v", name, p),
-					})
-				} else {
-					conn, err := net.Listen("tcp", ":"+p)
-					if conn != nil {
-						_ = conn.Close()
+
+				available := true
+				verified := true
+				for _, host := range bindingHosts(Port.HostIP, ipv6) {
+					if checked[proto+host+p] {
+						continue
+					}
+					checked[proto+host+p] = true
+
+					err := bindTest(proto, host, p)
+					if errors.Is(err, errUnverified) {
+						verified = false
+						continue
 					}
 					if err != nil {
-						blockingProcId, procName, err := getBlockingProcess(p, ctx, cli)
+						available = false
+						check := CompatibilityCheck{
+							Kind:     PortCheck,
+							Severity: SeverityError,
+							Service:  name,
+							Port:     p,
+							Protocol: proto,
+							HostIP:   host,
+							State:    false,
+						}
+						port := describePort(p, proto, host)
+						blocking, err := getBlockingProcess(p, proto, ctx, cli)
 						if err == nil {
-							messages = append(messages, CompatibilityCheck{
-								State:   false,
-								Message: fmt.Sprintf("%v is not able to start on port %v as process %d (%v) is already using this port", name, p, blockingProcId, procName),
-							})
+							check.PID = blocking.PID
+							check.Process = blocking.Process
+							check.Container = blocking.Container
+							procName := blocking.Process
+							if blocking.Container != "" {
+								procName = fmt.Sprintf("docker container %v", blocking.Container)
+							}
+							check.Message = fmt.Sprintf("%v is not able to start on port %v as process %d (%v) is already using this port", name, port, blocking.PID, procName)
 						} else {
-							messages = append(messages, CompatibilityCheck{
-								State:   false,
-								Message: fmt.Sprintf("%v is not able to start on port %v: %v", name, p, err),
-							})
+							check.Message = fmt.Sprintf("%v is not able to start on port %v: %v", name, port, err)
 						}
+						messages = append(messages, check)
+					}
+				}
+
+				if available {
+					check := CompatibilityCheck{
+						Kind:     PortCheck,
+						Severity: SeverityOK,
+						Service:  name,
+						Port:     p,
+						Protocol: proto,
+						HostIP:   Port.HostIP,
+						State:    true,
+						Message:  fmt.Sprintf("%v is able to start on port %v", name, describePort(p, proto, Port.HostIP)),
+					}
+					if !verified {
+						check.Severity = SeverityWarning
+						check.Message = fmt.Sprintf("%v should be able to start on port %v, but it could not be checked without elevated privileges", name, describePort(p, proto, Port.HostIP))
 					}
+					messages = append(messages, check)
 				}
 			}
 		}
@@ -83,44 +175,137 @@ func DryRun(ctx context.Context, cli *client.Client, c *Config) ([]Compatibility
 	return messages, nil
 }
 
-func getBlockingProcess(rawPort string, ctx context.Context, cli *client.Client) (int, string, error) {
+// bindingHosts will return the addresses a port binding on hostIP is
+// checked on, which are both wildcard addresses when it binds all of them.
+func bindingHosts(hostIP string, ipv6 bool) []string {
+	if hostIP != "" {
+		return []string{hostIP}
+	}
+	if ipv6 {
+		return []string{"0.0.0.0", "::"}
+	}
+	return []string{"0.0.0.0"}
+}
+
+// errUnverified is returned by bindTest when a port could not be checked.
+var errUnverified = errors.New("port could not be checked")
+
+// bindTest will attempt to bind a port on a given address and report an
+// error if it's already in use. Privileged TCP ports can't be bound by
+// unprivileged users, so a connection is attempted instead, which isn't
+// possible for UDP so errUnverified is returned.
+func bindTest(proto string, host string, port string) error {
+	family := "4"
+	loopback := "127.0.0.1"
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		family = "6"
+		loopback = "::1"
+	}
+	if host != "0.0.0.0" && host != "::" {
+		loopback = host
+	}
+	address := net.JoinHostPort(host, port)
+
+	var err error
+	if proto == "udp" {
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket(proto+family, address); conn != nil {
+			_ = conn.Close()
+		}
+	} else {
+		var ln net.Listener
+		if ln, err = net.Listen(proto+family, address); ln != nil {
+			_ = ln.Close()
+		}
+	}
+
+	if err == nil || !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+	if proto == "udp" {
+		return errUnverified
+	}
+
+	conn, dialErr := net.Dial(proto+family, net.JoinHostPort(loopback, port))
+	if dialErr != nil {
+		return nil
+	}
+	_ = conn.Close()
+	return fmt.Errorf("port %v is already in use", port)
+}
+
+// describePort will describe a port binding for reporting.
+func describePort(port string, proto string, host string) string {
+	description := port
+	if proto != "tcp" {
+		description = fmt.Sprintf("%v/%v", port, proto)
+	}
+	switch host {
+	case "", "0.0.0.0":
+	case "::":
+		description = fmt.Sprintf("%v over IPv6", description)
+	default:
+		description = fmt.Sprintf("%v on %v", description, host)
+	}
+	return description
+}
+
+// ipv6Supported will report if the host is able to bind IPv6 addresses.
+func ipv6Supported() bool {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	return true
+}
+
+// blockingProcess is the process found to be using a port.
+type blockingProcess struct {
+	PID       int
+	Process   string
+	Container string
+}
+
+func getBlockingProcess(rawPort string, proto string, ctx context.Context, cli *client.Client) (blockingProcess, error) {
 	p, err := nat.ParsePort(rawPort)
 	if err != nil {
-		return 0, "", err
+		return blockingProcess{}, err
 	}
 	port := uint32(p)
 
-	conns, err := ShirouNet.Connections("inet")
+	conns, err := ShirouNet.Connections(proto)
 	if err != nil {
-		panic(err)
+		return blockingProcess{}, err
 	}
 
 	for _, conn := range conns {
-		if conn.Laddr.Port != port || conn.Status != "LISTEN" {
+		// UDP sockets are never in the LISTEN state.
+		if conn.Laddr.Port != port || (proto == "tcp" && conn.Status != "LISTEN") {
 			continue
 		}
 
 		if conn.Pid == 0 {
-			return 0, "", fmt.Errorf("no PID found")
+			return blockingProcess{}, fmt.Errorf("no PID found")
 		}
 
 		proc, err := process.NewProcess(conn.Pid)
 		if err != nil {
-			return 0, "", fmt.Errorf("could not get process info for PID %d", conn.Pid)
+			return blockingProcess{}, fmt.Errorf("could not get process info for PID %d", conn.Pid)
 		}
 
-		name, _ := proc.Name()
-		if strings.Contains(name, "docker") {
-			containerName, _ := getContainerNameFromPort(port, ctx, cli)
-			name = fmt.Sprintf("docker container %v", containerName)
+		blocking := blockingProcess{PID: int(conn.Pid)}
+		blocking.Process, _ = proc.Name()
+		if strings.Contains(blocking.Process, "docker") {
+			blocking.Container, _ = getContainerNameFromPort(port, proto, ctx, cli)
 		}
-		return int(conn.Pid), name, err
+		return blocking, nil
 	}
 
-	return 0, "", fmt.Errorf("no process found listening on port %d", port)
+	return blockingProcess{}, fmt.Errorf("no process found listening on port %d", port)
 }
 
-func getContainerNameFromPort(port uint32, ctx context.Context, cli *client.Client) (string, error) {
+func getContainerNameFromPort(port uint32, proto string, ctx context.Context, cli *client.Client) (string, error) {
 	containers, err := cli.ContainerList(ctx, container.ListOptions{})
 	if err != nil {
 		return "", err
@@ -128,7 +313,7 @@ func getContainerNameFromPort(port uint32, ctx context.Context, cli *client.Clie
 
 	for _, c := range containers {
 		for _, p := range c.Ports {
-			if p.PublicPort == uint16(port) {
+			if p.PublicPort == uint16(port) && p.Type == proto {
 				return c.Names[0][1:], nil
 			}
 		}
@@ -0,0 +1,126 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/containerd/platforms"
+	"github.com/docker/docker/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
+)
+
+// PlatformChecks will check that the image of each service which isn't
+// running has a variant for the platform it will run as: the platform in
+// its pygmy.platform label, or the native platform of the daemon. When a
+// service without the label has no native variant it falls back to
+// running under emulation, and the label is set to the emulated platform.
+// Images which can't be inspected, such as when the registry can't be
+// reached, are not reported.
+func PlatformChecks(ctx context.Context, cli *client.Client, c *Config) ([]CompatibilityCheck, error) {
+
+	messages := []CompatibilityCheck{}
+
+	native, err := images.DaemonPlatform(ctx, cli)
+	if err != nil {
+		return messages, err
+	}
+
+	for _, key := range c.SortedServices {
+		service := c.Services[key]
+		if enabled, _ := service.GetFieldBool(ctx, cli, "enable"); !enabled {
+			continue
+		}
+		if running, _ := service.Status(ctx, cli); running {
+			continue
+		}
+		name, _ := service.GetFieldString(ctx, cli, "name")
+		image := service.Config.Image
+
+		requested := native
+		label := service.Config.Labels[containers.PlatformLabel]
+		if label != "" {
+			p, err := platforms.Parse(label)
+			if err != nil {
+				messages = append(messages, CompatibilityCheck{
+					Kind:     PlatformCheck,
+					Severity: SeverityError,
+					Service:  name,
+					Platform: label,
+					State:    false,
+					Message:  fmt.Sprintf("%v has an invalid platform %v: %v", name, label, err),
+				})
+				continue
+			}
+			requested = platforms.Normalize(p)
+		}
+
+		available, err := images.Platforms(ctx, cli, image)
+		if err != nil || len(available) == 0 {
+			continue
+		}
+
+		switch {
+		case images.Supports(available, requested):
+			messages = append(messages, CompatibilityCheck{
+				Kind:     PlatformCheck,
+				Severity: SeverityOK,
+				Service:  name,
+				Platform: platforms.Format(requested),
+				State:    true,
+				Message:  fmt.Sprintf("%v will run %v as %v", name, image, platforms.Format(requested)),
+			})
+		case label != "":
+			messages = append(messages, CompatibilityCheck{
+				Kind:     PlatformCheck,
+				Severity: SeverityError,
+				Service:  name,
+				Platform: platforms.Format(requested),
+				State:    false,
+				Message:  fmt.Sprintf("%v requests platform %v, but %v is only available for %v", name, platforms.Format(requested), image, formatPlatforms(available)),
+			})
+		default:
+			emulated, ok := images.Emulated(available)
+			if !ok {
+				messages = append(messages, CompatibilityCheck{
+					Kind:     PlatformCheck,
+					Severity: SeverityError,
+					Service:  name,
+					Platform: platforms.Format(requested),
+					State:    false,
+					Message:  fmt.Sprintf("%v can't run %v, which is only available for %v", name, image, formatPlatforms(available)),
+				})
+				continue
+			}
+			labels := make(map[string]string, len(service.Config.Labels)+1)
+			for k, v := range service.Config.Labels {
+				labels[k] = v
+			}
+			labels[containers.PlatformLabel] = platforms.Format(emulated)
+			service.Config.Labels = labels
+			c.Services[key] = service
+			messages = append(messages, CompatibilityCheck{
+				Kind:     PlatformCheck,
+				Severity: SeverityWarning,
+				Service:  name,
+				Platform: platforms.Format(emulated),
+				State:    true,
+				Message:  fmt.Sprintf("%v has no %v variant of %v, it will run as %v under emulation, which is slower", name, platforms.Format(requested), image, platforms.Format(emulated)),
+			})
+		}
+	}
+
+	return messages, nil
+}
+
+// formatPlatforms will list platforms for display.
+func formatPlatforms(available []v1.Platform) string {
+	formatted := make([]string, 0, len(available))
+	for _, p := range available {
+		formatted = append(formatted, platforms.Format(p))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,209 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/docker/docker/client"
+
+	"github.com/pygmystack/pygmy/internal/utils/state"
+)
+
+// PortRemap is a host port which was already in use and has been
+// replaced by a free port.
+type PortRemap struct {
+	Service string `json:"service"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// AutoPorts will replace host port bindings which are already in use with
+// free ports, preferring the ports assigned on a previous run, and update
+// the pygmy.url labels to match. Running services keep their bindings, but
+// the remaps persisted for them are still returned so URLs can be
+// reported correctly.
+func AutoPorts(ctx context.Context, cli *client.Client, c *Config) ([]PortRemap, error) {
+	s, err := state.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// The ports of a remote daemon are published on the remote host, where
+	// only TCP ports can be checked.
+	ipv6 := ipv6Supported()
+	available := func(proto string, hostIP string, port string) bool {
+		return portAvailable(proto, hostIP, port, ipv6)
+	}
+	if c.Remote.Enabled() {
+		available = func(proto string, hostIP string, port string) bool {
+			return proto != "tcp" || remotePortAvailable(c.Remote.Host, port)
+		}
+	}
+
+	remaps := []PortRemap{}
+	for name, service := range c.Services {
+		if enabled, _ := service.GetFieldBool(ctx, cli, "enable"); !enabled {
+			continue
+		}
+
+		if running, _ := service.Status(ctx, cli); running {
+			for from, to := range s.Ports[name] {
+				remaps = append(remaps, PortRemap{Service: name, From: from, To: to})
+			}
+			continue
+		}
+
+		// Dual-stack bindings list the same host port once per address,
+		// and a service may publish it over both TCP and UDP. All of them
+		// must be moved to the same free port, so the TCP bindings are
+		// remapped first and the UDP bindings follow them. Bindings are
+		// checked as PortChecks checks them, so both agree on what is in
+		// use.
+		assigned := map[string]string{}
+		for _, proto := range []string{"tcp", "udp"} {
+			for port, bindings := range service.HostConfig.PortBindings {
+				if port.Proto() != proto {
+					continue
+				}
+				for n, binding := range bindings {
+					from := binding.HostPort
+					if from == "" {
+						continue
+					}
+					if to, ok := assigned[from]; ok {
+						bindings[n].HostPort = to
+						continue
+					}
+					if available(proto, binding.HostIP, from) {
+						continue
+					}
+					// The resolvers send queries to the port dnsmasq
+					// listens on by default, so it can't be moved.
+					if name == "amazeeio-dnsmasq" {
+						return remaps, fmt.Errorf("port %v of %v is already in use, and can't be replaced as the resolvers are configured to use it", from, name)
+					}
+					to := s.Ports[name][from]
+					bindable := func(p string) bool {
+						return available(proto, binding.HostIP, p)
+					}
+					if to == "" || !bindable(to) {
+						free, err := freePort(bindable)
+						if err != nil {
+							return remaps, fmt.Errorf("could not find a free port to replace port %v of %v: %w", from, name, err)
+						}
+						to = strconv.Itoa(free)
+					}
+					assigned[from] = to
+					bindings[n].HostPort = to
+					remaps = append(remaps, PortRemap{Service: name, From: from, To: to})
+				}
+			}
+		}
+	}
+
+	sort.Slice(remaps, func(i, j int) bool {
+		if remaps[i].Service != remaps[j].Service {
+			return remaps[i].Service < remaps[j].Service
+		}
+		return remaps[i].From < remaps[j].From
+	})
+
+	// Forwarded ports keep their local port, see RemapForwards.
+	if c.Remote.Forwarded() {
+		return remaps, nil
+	}
+
+	for _, service := range c.Services {
+		if u, ok := service.Config.Labels["pygmy.url"]; ok {
+			service.Config.Labels["pygmy.url"] = RemapURL(u, remaps)
+		}
+	}
+
+	return remaps, nil
+}
+
+// SavePortRemaps will persist the remapped ports in the state file so
+// they are reused on the next run.
+func SavePortRemaps(remaps []PortRemap) error {
+	return state.Update(func(s *state.State) {
+		s.Ports = make(map[string]map[string]string)
+		for _, remap := range remaps {
+			if s.Ports[remap.Service] == nil {
+				s.Ports[remap.Service] = make(map[string]string)
+			}
+			s.Ports[remap.Service][remap.From] = remap.To
+		}
+	})
+}
+
+// LoadPortRemaps will return the remapped ports persisted in the state
+// file.
+func LoadPortRemaps() []PortRemap {
+	s, _ := state.Load()
+	remaps := []PortRemap{}
+	for service, ports := range s.Ports {
+		for from, to := range ports {
+			remaps = append(remaps, PortRemap{Service: service, From: from, To: to})
+		}
+	}
+	return remaps
+}
+
+// RemapURL will add the remapped haproxy port to a URL which is routed
+// through haproxy on a default port which has been remapped.
+func RemapURL(rawURL string, remaps []PortRemap) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	for _, remap := range remaps {
+		if remap.Service == "amazeeio-haproxy" && remap.From == port {
+			u.Host = net.JoinHostPort(u.Hostname(), remap.To)
+			return u.String()
+		}
+	}
+	return rawURL
+}
+
+// portAvailable will report if a host port can be bound for a binding
+// on hostIP, using the same checks as PortChecks. Ports which could not
+// be checked without elevated privileges are reported as available.
+func portAvailable(proto string, hostIP string, port string, ipv6 bool) bool {
+	for _, host := range bindingHosts(hostIP, ipv6) {
+		if err := bindTest(proto, host, port); err != nil && !errors.Is(err, errUnverified) {
+			return false
+		}
+	}
+	return true
+}
+
+// freePort will return a port which is currently free on this machine,
+// and for which available also reports true.
+func freePort(available func(port string) bool) (int, error) {
+	for attempt := 0; attempt < 10; attempt++ {
+		ln, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return 0, err
+		}
+		port := ln.Addr().(*net.TCPAddr).Port
+		_ = ln.Close()
+		if available(strconv.Itoa(port)) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port was found")
+}
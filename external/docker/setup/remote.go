@@ -0,0 +1,145 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	dockercontext "github.com/pygmystack/pygmy/internal/runtime/docker/internals/context"
+	"github.com/pygmystack/pygmy/internal/utils/forward"
+)
+
+// remoteHost will return the address the services of a remote daemon are
+// reached on, or an empty string when the daemon runs on this machine.
+// address is the configured remote.address, which is used instead of the
+// address of the daemon host.
+func remoteHost(ctx context.Context, address string) (string, error) {
+	endpoint, err := dockercontext.Resolve()
+	if err != nil || !endpoint.Remote() {
+		return "", err
+	}
+	if address != "" {
+		return address, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	return endpoint.RemoteIP(ctx)
+}
+
+// Forward will return the SSH port forwards to the remote daemon host,
+// through remote.ssh or the host of an ssh:// daemon.
+func Forward(c *Config) (forward.Forward, error) {
+	destination := c.Remote.SSH
+	if destination == "" {
+		endpoint, err := dockercontext.Resolve()
+		if err != nil {
+			return forward.Forward{}, err
+		}
+		if !strings.HasPrefix(endpoint.Host, "ssh://") {
+			return forward.Forward{}, fmt.Errorf("ports can only be forwarded from a daemon reached over ssh://, set remote.ssh to forward them from %v", endpoint.Host)
+		}
+		destination = endpoint.Host
+	}
+	args, err := dockercontext.SSHArgs(destination)
+	if err != nil {
+		return forward.Forward{}, err
+	}
+
+	ports := c.Remote.Ports
+	if len(ports) == 0 {
+		ports = forward.DefaultPorts
+	}
+	parsed, err := forward.ParsePorts(ports)
+	if err != nil {
+		return forward.Forward{}, err
+	}
+	return forward.New(args, RemapForwards(parsed, LoadPortRemaps())), nil
+}
+
+// RemapForwards will forward to the remapped haproxy and mailhog ports on
+// the remote host, when their default ports were already in use there.
+func RemapForwards(ports []forward.Port, remaps []PortRemap) []forward.Port {
+	remapped := make([]forward.Port, 0, len(ports))
+	for _, port := range ports {
+		for _, remap := range remaps {
+			if remap.Service != "amazeeio-haproxy" && remap.Service != "amazeeio-mailhog" {
+				continue
+			}
+			if to, err := strconv.Atoi(remap.To); err == nil && remap.From == strconv.Itoa(port.Remote) {
+				port.Remote = to
+			}
+		}
+		remapped = append(remapped, port)
+	}
+	return remapped
+}
+
+// remotePortChecks will check the ports of the services which aren't
+// running are free on the remote host, which is done by connecting to
+// them. UDP ports can't be checked this way and are reported as
+// warnings.
+func remotePortChecks(ctx context.Context, cli *client.Client, c *Config) []CompatibilityCheck {
+	messages := []CompatibilityCheck{}
+
+	for _, key := range c.SortedServices {
+		service := c.Services[key]
+		name, _ := service.GetFieldString(ctx, cli, "name")
+		if enabled, _ := service.GetFieldBool(ctx, cli, "enable"); !enabled {
+			continue
+		}
+		if running, _ := service.Status(ctx, cli); running {
+			continue
+		}
+
+		checked := map[string]bool{}
+		for binding, ports := range service.HostConfig.PortBindings {
+			proto := binding.Proto()
+			for _, port := range ports {
+				p := port.HostPort
+				if p == "" || checked[proto+p] || (proto != "tcp" && proto != "udp") {
+					continue
+				}
+				checked[proto+p] = true
+
+				check := CompatibilityCheck{
+					Kind:     PortCheck,
+					Severity: SeverityOK,
+					Service:  name,
+					Port:     p,
+					Protocol: proto,
+					HostIP:   c.Remote.Host,
+					State:    true,
+					Message:  fmt.Sprintf("%v is able to start on port %v", name, describePort(p, proto, c.Remote.Host)),
+				}
+				switch {
+				case proto == "udp":
+					check.Severity = SeverityWarning
+					check.Message = fmt.Sprintf("%v should be able to start on port %v, but UDP ports can't be checked on a remote host", name, describePort(p, proto, c.Remote.Host))
+				case !remotePortAvailable(c.Remote.Host, p):
+					check.Severity = SeverityError
+					check.State = false
+					check.Message = fmt.Sprintf("%v is not able to start on port %v as it is already in use on the remote host", name, describePort(p, proto, c.Remote.Host))
+				}
+				messages = append(messages, check)
+			}
+		}
+	}
+
+	return messages
+}
+
+// remotePortAvailable will report if nothing accepts connections on a TCP
+// port of the remote host.
+func remotePortAvailable(host string, port string) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), 2*time.Second)
+	if err != nil {
+		return true
+	}
+	_ = conn.Close()
+	return false
+}
@@ -0,0 +1,10 @@
+package setup
+
+import (
+	_ "embed"
+)
+
+// StatusSchema is the JSON Schema describing StatusJSON.
+//
+//go:embed status.schema.json
+var StatusSchema []byte
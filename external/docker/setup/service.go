@@ -3,6 +3,7 @@ package setup
 import (
 	"fmt"
 
+	"github.com/docker/go-connections/nat"
 	"github.com/imdario/mergo"
 
 	dockerruntime "github.com/pygmystack/pygmy/internal/runtime/docker"
@@ -23,3 +24,26 @@ func GetService(s dockerruntime.Service, c dockerruntime.Service) dockerruntime.
 	Service, _ := mergeService(s, &c)
 	return *Service
 }
+
+// dualStack will replace port bindings on all addresses with explicit
+// bindings on both 0.0.0.0 and ::, so the ports are reachable over IPv4
+// and IPv6. Bindings to a specific address are left as they are.
+func dualStack(bindings nat.PortMap) nat.PortMap {
+	if bindings == nil {
+		return nil
+	}
+	result := nat.PortMap{}
+	for port, list := range bindings {
+		for _, binding := range list {
+			if binding.HostIP != "" {
+				result[port] = append(result[port], binding)
+				continue
+			}
+			result[port] = append(result[port],
+				nat.PortBinding{HostIP: "0.0.0.0", HostPort: binding.HostPort},
+				nat.PortBinding{HostIP: "::", HostPort: binding.HostPort},
+			)
+		}
+	}
+	return result
+}
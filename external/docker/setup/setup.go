@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"runtime"
 	"sort"
@@ -16,6 +17,7 @@ import (
 	"github.com/spf13/viper"
 
 	dockerruntime "github.com/pygmystack/pygmy/internal/runtime/docker"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/volumes"
 	"github.com/pygmystack/pygmy/internal/service/docker/dnsmasq"
 	"github.com/pygmystack/pygmy/internal/service/docker/haproxy"
@@ -26,6 +28,7 @@ import (
 	"github.com/pygmystack/pygmy/internal/utils/color"
 	"github.com/pygmystack/pygmy/internal/utils/network/docker"
 	"github.com/pygmystack/pygmy/internal/utils/resolv"
+	"github.com/pygmystack/pygmy/internal/utils/state"
 )
 
 // ImportDefaults is an exported function which allows third-party applications
@@ -109,6 +112,13 @@ func Setup(ctx context.Context, cli *client.Client, c *Config) {
 		c.Domain = viper.GetString("domain")
 	}
 
+	// A daemon on another machine publishes ports on its own host, which
+	// DNS answers and resolvers point at instead of this machine.
+	remote, err := remoteHost(ctx, viper.GetString("remote.address"))
+	if err != nil {
+		fmt.Printf("could not find the address of the remote Docker host: %v\n", err)
+	}
+
 	// Resolvers don't have hard defaults defined which
 	// are mergable. So we set them in viper before
 	// unmarshalling the config so that config specified
@@ -116,8 +126,21 @@ func Setup(ctx context.Context, cli *client.Client, c *Config) {
 	// be overridden if it's not specified.
 	if viper.GetBool("defaults") {
 
+		// With IPv6 enabled, dnsmasq is also reachable over ::1. The
+		// dnsmasq of a remote daemon is reached on the remote host.
+		nameserver := "127.0.0.1"
+		if remote != "" {
+			nameserver = remote
+		}
+		nameservers := fmt.Sprintf("nameserver %s\n", nameserver)
+		dns := fmt.Sprintf("DNS=%s\n", net.JoinHostPort(nameserver, "6053"))
+		if (viper.GetBool("ipv6") || c.IPv6) && remote == "" {
+			nameservers += "nameserver ::1\n"
+			dns += "DNS=[::1]:6053\n"
+		}
+
 		var ResolvMacOS = resolv.Resolv{
-			Data:    fmt.Sprintf("# Generated by amazeeio pygmy\nnameserver 127.0.0.1\ndomain %s\nport 6053\n", c.Domain),
+			Data:    fmt.Sprintf("# Generated by amazeeio pygmy\n%sdomain %s\nport 6053\n", nameservers, c.Domain),
 			Enabled: true,
 			File:    c.Domain,
 			Folder:  "/etc/resolver",
@@ -125,7 +148,7 @@ func Setup(ctx context.Context, cli *client.Client, c *Config) {
 		}
 
 		var ResolvLinux = resolv.Resolv{
-			Data:    fmt.Sprintf("# Generated by amazeeio pygmy\n[Resolve]\nDNS=127.0.0.1:6053\nDomains=~%s\n", c.Domain),
+			Data:    fmt.Sprintf("# Generated by amazeeio pygmy\n[Resolve]\n%sDomains=~%s\n", dns, c.Domain),
 			Enabled: true,
 			File:    fmt.Sprintf("%s.conf", c.Domain),
 			Folder:  "/usr/lib/systemd/resolved.conf.d",
@@ -152,10 +175,24 @@ func Setup(ctx context.Context, cli *client.Client, c *Config) {
 		fmt.Println(e)
 	}
 
+	c.Remote.Host = remote
+
 	if e = setupTLS(c); e != nil {
 		fmt.Println(e)
 	}
 
+	switch c.Agent.Mode {
+	case "", AgentModeContainer, AgentModeForward:
+	default:
+		fmt.Printf("unknown agent mode '%v', expected '%v' or '%v'\n", c.Agent.Mode, AgentModeContainer, AgentModeForward)
+	}
+
+	// A remote daemon can't mount the socket of the host agent.
+	if c.Agent.Forwarded() && c.Remote.Enabled() {
+		fmt.Printf("the host SSH agent can't be forwarded to the remote Docker host %v, the agent runs in a container instead\n", c.Remote.Host)
+		c.Agent.Mode = AgentModeContainer
+	}
+
 	if c.Defaults {
 
 		// If Services have been provided in complete or partially,
@@ -165,9 +202,20 @@ func Setup(ctx context.Context, cli *client.Client, c *Config) {
 			c.Services = make(map[string]dockerruntime.Service, 6)
 		}
 
-		ImportDefaults(ctx, cli, c, "amazeeio-ssh-agent", agent.New())
-		ImportDefaults(ctx, cli, c, "amazeeio-ssh-agent-add-key", key.NewAdder())
-		ImportDefaults(ctx, cli, c, "amazeeio-dnsmasq", dnsmasq.New(&dockerruntime.Params{Domain: c.Domain}))
+		// In forward mode the host agent is proxied and keys are never
+		// added, so there is no need for the key adder.
+		if c.Agent.Forwarded() {
+			if c.Agent.Socket == "" {
+				if c.Agent.Socket, e = agent.HostSocket(); e != nil {
+					fmt.Println(e)
+				}
+			}
+			ImportDefaults(ctx, cli, c, "amazeeio-ssh-agent", agent.NewForwarder(c.Agent.Socket, c.Agent.Image))
+		} else {
+			ImportDefaults(ctx, cli, c, "amazeeio-ssh-agent", agent.New())
+			ImportDefaults(ctx, cli, c, "amazeeio-ssh-agent-add-key", key.NewAdder())
+		}
+		ImportDefaults(ctx, cli, c, "amazeeio-dnsmasq", dnsmasq.New(&dockerruntime.Params{Domain: c.Domain, IPv6: c.IPv6 && !c.Remote.Enabled(), Address: c.Remote.Answer()}))
 		ImportDefaults(ctx, cli, c, "amazeeio-haproxy", haproxy.New(&dockerruntime.Params{Domain: c.Domain, TLSCertPath: c.TLSCertPath}))
 		ImportDefaults(ctx, cli, c, "amazeeio-mailhog", mailhog.New(&dockerruntime.Params{Domain: c.Domain, TLSCertPath: c.TLSCertPath}))
 
@@ -197,6 +245,20 @@ func Setup(ctx context.Context, cli *client.Client, c *Config) {
 			c.Networks["amazeeio-network"] = GetNetwork(docker.New(), c.Networks["amazeeio-network"])
 		}
 
+		// Dual-stack networking adds an IPv6 subnet to the network and
+		// publishes the default ports on both address families.
+		if c.IPv6 {
+			if network, ok := c.Networks["amazeeio-network"]; ok {
+				c.Networks["amazeeio-network"] = docker.WithIPv6(network)
+			}
+			for _, name := range []string{"amazeeio-dnsmasq", "amazeeio-haproxy", "amazeeio-mailhog"} {
+				if service, ok := c.Services[name]; ok {
+					service.HostConfig.PortBindings = dualStack(service.HostConfig.PortBindings)
+					c.Services[name] = service
+				}
+			}
+		}
+
 		// Ensure Volumes has a at least a zero value.
 		if c.Volumes == nil {
 			c.Volumes = make(map[string]volume.Volume)
@@ -250,6 +312,52 @@ func Setup(ctx context.Context, cli *client.Client, c *Config) {
 		c.Services[name] = service
 	}
 
+	// Pull the pygmystack images through the registry mirror.
+	if c.Registry.Mirror != "" {
+		if _, err := images.Normalize(images.Mirror("pygmystack/pygmy", c.Registry.Mirror)); err != nil {
+			fmt.Printf("invalid registry mirror '%v': %v\n", c.Registry.Mirror, err)
+			os.Exit(2)
+		}
+		for name, service := range c.Services {
+			service.Config.Image = images.Mirror(service.Config.Image, c.Registry.Mirror)
+			service.Image = service.Config.Image
+			c.Services[name] = service
+		}
+	}
+
+	// Label everything pygmy creates with the identifier of this
+	// installation, so that clean only ever touches its own resources.
+	if c.InstanceID == "" {
+		if id, err := state.InstanceID(); err == nil {
+			c.InstanceID = id
+		} else {
+			fmt.Println(err)
+		}
+	}
+	if c.InstanceID != "" {
+		for name, service := range c.Services {
+			if service.Config.Labels == nil {
+				service.Config.Labels = make(map[string]string)
+			}
+			service.Config.Labels[dockerruntime.InstanceLabel] = c.InstanceID
+			c.Services[name] = service
+		}
+		for name, network := range c.Networks {
+			if network.Labels == nil {
+				network.Labels = make(map[string]string)
+			}
+			network.Labels[dockerruntime.InstanceLabel] = c.InstanceID
+			c.Networks[name] = network
+		}
+		for name, volume := range c.Volumes {
+			if volume.Labels == nil {
+				volume.Labels = make(map[string]string)
+			}
+			volume.Labels[dockerruntime.InstanceLabel] = c.InstanceID
+			c.Volumes[name] = volume
+		}
+	}
+
 	// Determine the slice of sorted services
 	c.SortedServices = GetServicesSorted(ctx, cli, c)
 }
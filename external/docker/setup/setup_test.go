@@ -1,13 +1,21 @@
 package setup_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/pygmystack/pygmy/external/docker/setup"
 	"github.com/pygmystack/pygmy/internal/runtime/docker"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
+	"github.com/pygmystack/pygmy/internal/utils/forward"
 )
 
 // Tests the setup process.
@@ -79,3 +87,300 @@ func TestSetupSSHAgentImageOverride(t *testing.T) {
 		So(c.Services["amazeeio-ssh-agent-add-key"].Config.Image, ShouldEqual, "ghcr.io/pygmystack/ssh-agent:main")
 	})
 }
+
+func TestSetupIPv6(t *testing.T) {
+	c := &setup.Config{IPv6: true}
+
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup.Setup(ctx, cli, c)
+
+	Convey("IPv6 enables dual-stack networking and port bindings", t, func() {
+		So(c.Networks["amazeeio-network"].EnableIPv6, ShouldBeTrue)
+		So(c.Networks["amazeeio-network"].IPAM.Config, ShouldHaveLength, 2)
+		So(c.Services["amazeeio-haproxy"].HostConfig.PortBindings["80/tcp"], ShouldHaveLength, 2)
+		So(c.Services["amazeeio-haproxy"].HostConfig.PortBindings["80/tcp"][1].HostIP, ShouldEqual, "::")
+		So(c.Services["amazeeio-dnsmasq"].Config.Cmd, ShouldContain, "/docker.amazee.io/::1")
+	})
+}
+
+func TestSetupRegistryMirror(t *testing.T) {
+	c := &setup.Config{Registry: setup.Registry{Mirror: "mirror.example.com:5000"}}
+
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup.Setup(ctx, cli, c)
+
+	Convey("The pygmystack images are pulled through the registry mirror", t, func() {
+		So(c.Services["amazeeio-haproxy"].Config.Image, ShouldStartWith, "mirror.example.com:5000/pygmystack/haproxy")
+		So(c.Services["amazeeio-ssh-agent"].Image, ShouldStartWith, "mirror.example.com:5000/pygmystack/ssh-agent")
+		So(c.Services["amazeeio-ssh-agent-add-key"].Config.Image, ShouldStartWith, "mirror.example.com:5000/pygmystack/ssh-agent")
+	})
+}
+
+func TestRemapURL(t *testing.T) {
+	remaps := []setup.PortRemap{
+		{Service: "amazeeio-haproxy", From: "80", To: "8080"},
+		{Service: "amazeeio-mailhog", From: "1025", To: "1026"},
+	}
+
+	Convey("URLs routed through a remapped haproxy port include the new port", t, func() {
+		So(setup.RemapURL("http://docker.amazee.io/stats", remaps), ShouldEqual, "http://docker.amazee.io:8080/stats")
+		So(setup.RemapURL("http://mailhog.docker.amazee.io:80", remaps), ShouldEqual, "http://mailhog.docker.amazee.io:8080")
+		So(setup.RemapURL("https://docker.amazee.io", remaps), ShouldEqual, "https://docker.amazee.io")
+		So(setup.RemapURL("http://example.com:1025", remaps), ShouldEqual, "http://example.com:1025")
+		So(setup.RemapURL("not a url", remaps), ShouldEqual, "not a url")
+	})
+}
+
+func TestPortChecksUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	c := &setup.Config{
+		Services: map[string]docker.Service{
+			"example-udp": {
+				Config: container.Config{
+					Labels: map[string]string{
+						"pygmy.name":   "example-udp",
+						"pygmy.enable": "true",
+					},
+				},
+				HostConfig: container.HostConfig{
+					PortBindings: nat.PortMap{
+						nat.Port(fmt.Sprintf("%d/udp", port)): []nat.PortBinding{{HostPort: fmt.Sprint(port)}},
+					},
+				},
+			},
+		},
+	}
+
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks, _ := setup.PortChecks(ctx, cli, c)
+
+	Convey("UDP ports in use are reported", t, func() {
+		So(checks, ShouldNotBeEmpty)
+		So(checks[0].State, ShouldBeFalse)
+		So(checks[0].Kind, ShouldEqual, setup.PortCheck)
+		So(checks[0].Severity, ShouldEqual, setup.SeverityError)
+		So(checks[0].Service, ShouldEqual, "example-udp")
+		So(checks[0].Port, ShouldEqual, fmt.Sprint(port))
+		So(checks[0].Protocol, ShouldEqual, "udp")
+		So(checks[0].HostIP, ShouldEqual, "0.0.0.0")
+		So(checks[0].Message, ShouldContainSubstring, fmt.Sprintf("example-udp is not able to start on port %d/udp", port))
+	})
+}
+
+func TestStatusSchema(t *testing.T) {
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Const int `json:"const"`
+		} `json:"properties"`
+	}
+
+	Convey("The status schema matches StatusJSON", t, func() {
+		So(json.Unmarshal(setup.StatusSchema, &schema), ShouldBeNil)
+		So(schema.Properties["schema_version"].Const, ShouldEqual, setup.StatusSchemaVersion)
+
+		data, err := json.Marshal(setup.StatusJSON{})
+		So(err, ShouldBeNil)
+		fields := map[string]interface{}{}
+		So(json.Unmarshal(data, &fields), ShouldBeNil)
+		So(fields, ShouldHaveLength, len(schema.Required))
+		for _, field := range schema.Required {
+			So(fields, ShouldContainKey, field)
+		}
+	})
+}
+
+func TestKeyLifetime(t *testing.T) {
+	Convey("Key lifetimes are parsed as durations", t, func() {
+		d, err := setup.Key{Path: "id_rsa"}.LifetimeDuration()
+		So(err, ShouldBeNil)
+		So(d, ShouldEqual, 0)
+
+		d, err = setup.Key{Path: "id_rsa", Lifetime: "8h"}.LifetimeDuration()
+		So(err, ShouldBeNil)
+		So(d, ShouldEqual, 8*time.Hour)
+
+		_, err = setup.Key{Path: "id_rsa", Lifetime: "eight hours"}.LifetimeDuration()
+		So(err, ShouldNotBeNil)
+
+		_, err = setup.Key{Path: "id_rsa", Lifetime: "500ms"}.LifetimeDuration()
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestPortChecksRemote(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	c := &setup.Config{
+		Remote: setup.Remote{Host: "127.0.0.1"},
+		Services: map[string]docker.Service{
+			"example-remote": {
+				Config: container.Config{
+					Labels: map[string]string{
+						"pygmy.name":   "example-remote",
+						"pygmy.enable": "true",
+					},
+				},
+				HostConfig: container.HostConfig{
+					PortBindings: nat.PortMap{
+						nat.Port(fmt.Sprintf("%d/tcp", port)): []nat.PortBinding{{HostPort: fmt.Sprint(port)}},
+						"53/udp":                              []nat.PortBinding{{HostPort: "6053"}},
+					},
+				},
+			},
+		},
+		SortedServices: []string{"example-remote"},
+	}
+
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks, _ := setup.PortChecks(ctx, cli, c)
+
+	Convey("Ports of a remote daemon are checked on the remote host", t, func() {
+		So(checks, ShouldHaveLength, 2)
+		for _, check := range checks {
+			So(check.HostIP, ShouldEqual, "127.0.0.1")
+			switch check.Protocol {
+			case "tcp":
+				So(check.State, ShouldBeFalse)
+				So(check.Severity, ShouldEqual, setup.SeverityError)
+				So(check.Message, ShouldContainSubstring, "already in use on the remote host")
+			case "udp":
+				So(check.State, ShouldBeTrue)
+				So(check.Severity, ShouldEqual, setup.SeverityWarning)
+			}
+		}
+	})
+}
+
+func TestRemote(t *testing.T) {
+	Convey("The domain resolves to the remote host unless ports are forwarded", t, func() {
+		So(setup.Remote{}.Answer(), ShouldEqual, "127.0.0.1")
+		So(setup.Remote{}.Forwarded(), ShouldBeFalse)
+		So(setup.Remote{Host: "192.168.64.2"}.Answer(), ShouldEqual, "192.168.64.2")
+		So(setup.Remote{Host: "192.168.64.2", Forward: true}.Answer(), ShouldEqual, "127.0.0.1")
+		So(setup.Remote{Host: "192.168.64.2", Forward: true}.Forwarded(), ShouldBeTrue)
+	})
+
+	Convey("Forwards follow the remapped haproxy and mailhog ports", t, func() {
+		ports := []forward.Port{{Local: 80, Remote: 80}, {Local: 443, Remote: 443}, {Local: 1025, Remote: 1025}}
+		remaps := []setup.PortRemap{
+			{Service: "amazeeio-haproxy", From: "80", To: "8080"},
+			{Service: "amazeeio-mailhog", From: "1025", To: "1026"},
+			{Service: "amazeeio-dnsmasq", From: "443", To: "8443"},
+		}
+		So(setup.RemapForwards(ports, remaps), ShouldResemble, []forward.Port{{Local: 80, Remote: 8080}, {Local: 443, Remote: 443}, {Local: 1025, Remote: 1026}})
+	})
+
+	Convey("Ports can only be forwarded from an ssh:// daemon without remote.ssh", t, func() {
+		t.Setenv("PYGMY_STATE", filepath.Join(t.TempDir(), "state.json"))
+		t.Setenv("DOCKER_HOST", "tcp://192.168.64.2:2375")
+		_, err := setup.Forward(&setup.Config{})
+		So(err, ShouldNotBeNil)
+
+		f, err := setup.Forward(&setup.Config{Remote: setup.Remote{SSH: "docker@vm", Ports: []string{"8080:80"}}})
+		So(err, ShouldBeNil)
+		So(f.Destination, ShouldResemble, []string{"docker@vm"})
+		So(f.Ports, ShouldResemble, []forward.Port{{Local: 8080, Remote: 80}})
+
+		t.Setenv("DOCKER_HOST", "ssh://docker@vm:2222")
+		f, err = setup.Forward(&setup.Config{})
+		So(err, ShouldBeNil)
+		So(f.Destination, ShouldResemble, []string{"-l", "docker", "-p", "2222", "vm"})
+		So(f.Ports, ShouldHaveLength, 3)
+	})
+}
+
+func TestAutoPorts(t *testing.T) {
+	t.Setenv("PYGMY_STATE", filepath.Join(t.TempDir(), "state.json"))
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	port := fmt.Sprint(ln.Addr().(*net.TCPAddr).Port)
+
+	cli, ctx, err := internals.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := func(name string) docker.Service {
+		return docker.Service{
+			Config: container.Config{
+				Labels: map[string]string{
+					"pygmy.name":   name,
+					"pygmy.enable": "true",
+				},
+			},
+			HostConfig: container.HostConfig{
+				PortBindings: nat.PortMap{
+					"53/tcp": []nat.PortBinding{{HostPort: port}},
+					"53/udp": []nat.PortBinding{{HostPort: port}},
+				},
+			},
+		}
+	}
+
+	Convey("TCP and UDP bindings of a port in use are remapped together", t, func() {
+		c := &setup.Config{Services: map[string]docker.Service{"example-dns": service("example-dns")}}
+		remaps, err := setup.AutoPorts(ctx, cli, c)
+		So(err, ShouldBeNil)
+		So(remaps, ShouldHaveLength, 1)
+		So(remaps[0].From, ShouldEqual, port)
+		bindings := c.Services["example-dns"].HostConfig.PortBindings
+		So(bindings["53/tcp"][0].HostPort, ShouldEqual, remaps[0].To)
+		So(bindings["53/udp"][0].HostPort, ShouldEqual, remaps[0].To)
+	})
+
+	Convey("UDP bindings of a port in use are remapped as PortChecks reports them", t, func() {
+		conn, err := net.ListenPacket("udp", ":0")
+		So(err, ShouldBeNil)
+		defer func() { _ = conn.Close() }()
+		udp := fmt.Sprint(conn.LocalAddr().(*net.UDPAddr).Port)
+
+		s := service("example-syslog")
+		s.HostConfig.PortBindings = nat.PortMap{"514/udp": []nat.PortBinding{{HostPort: udp}}}
+		c := &setup.Config{Services: map[string]docker.Service{"example-syslog": s}}
+		remaps, err := setup.AutoPorts(ctx, cli, c)
+		So(err, ShouldBeNil)
+		So(remaps, ShouldHaveLength, 1)
+		So(remaps[0].From, ShouldEqual, udp)
+		So(c.Services["example-syslog"].HostConfig.PortBindings["514/udp"][0].HostPort, ShouldEqual, remaps[0].To)
+	})
+
+	Convey("The dnsmasq port is not remapped", t, func() {
+		c := &setup.Config{Services: map[string]docker.Service{"amazeeio-dnsmasq": service("amazeeio-dnsmasq")}}
+		_, err := setup.AutoPorts(ctx, cli, c)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "resolvers")
+		So(c.Services["amazeeio-dnsmasq"].HostConfig.PortBindings["53/udp"][0].HostPort, ShouldEqual, port)
+	})
+}
@@ -0,0 +1,150 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	networktypes "github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/networks"
+	"github.com/pygmystack/pygmy/internal/utils/network/subnet"
+	"github.com/pygmystack/pygmy/internal/utils/state"
+)
+
+// SubnetChecks will check the subnets of networks which don't exist yet
+// against the networks in use by the host (including VPN routes) and by
+// Docker, before Pygmy attempts to create them. When automatic selection
+// is enabled, a free subnet is assigned to each network instead,
+// preferring the subnet selected on a previous run.
+func SubnetChecks(ctx context.Context, cli *client.Client, c *Config) ([]CompatibilityCheck, error) {
+
+	messages := []CompatibilityCheck{}
+
+	used, err := usedSubnets(ctx, cli)
+	if err != nil {
+		return messages, err
+	}
+
+	s, _ := state.Load()
+
+	for name, Network := range c.Networks {
+		if Network.Name == "" {
+			continue
+		}
+		if exists, _ := networks.Status(ctx, cli, Network.Name); exists {
+			continue
+		}
+
+		if c.Subnet.Auto {
+			selected := previousSubnet(s.Subnets[Network.Name], used)
+			if selected == nil {
+				selected, err = subnet.Select(c.Subnet.Pool, used)
+				if err != nil {
+					messages = append(messages, CompatibilityCheck{
+						Kind:     SubnetCheck,
+						Severity: SeverityError,
+						Network:  Network.Name,
+						State:    false,
+						Message:  fmt.Sprintf("%v could not be assigned a subnet: %v", Network.Name, err),
+					})
+					continue
+				}
+			}
+			setSubnet(&Network, selected)
+			c.Networks[name] = Network
+			used = append(used, subnet.Used{Network: selected, Source: fmt.Sprintf("docker network %s", Network.Name)})
+			messages = append(messages, CompatibilityCheck{
+				Kind:     SubnetCheck,
+				Severity: SeverityOK,
+				Network:  Network.Name,
+				Subnet:   selected.String(),
+				State:    true,
+				Message:  fmt.Sprintf("%v will use subnet %v", Network.Name, selected),
+			})
+			continue
+		}
+
+		for _, config := range Network.IPAM.Config {
+			_, configured, err := net.ParseCIDR(config.Subnet)
+			if err != nil {
+				continue
+			}
+			conflicts := subnet.Conflicts(configured, used)
+			if len(conflicts) == 0 {
+				messages = append(messages, CompatibilityCheck{
+					Kind:     SubnetCheck,
+					Severity: SeverityOK,
+					Network:  Network.Name,
+					Subnet:   configured.String(),
+					State:    true,
+					Message:  fmt.Sprintf("%v is able to use subnet %v", Network.Name, configured),
+				})
+				continue
+			}
+			var conflicting []string
+			for _, conflict := range conflicts {
+				conflicting = append(conflicting, conflict.String())
+			}
+			messages = append(messages, CompatibilityCheck{
+				Kind:     SubnetCheck,
+				Severity: SeverityError,
+				Network:  Network.Name,
+				Subnet:   configured.String(),
+				State:    false,
+				Message:  fmt.Sprintf("%v is not able to use subnet %v as it overlaps %v, configure a different subnet or enable subnet.auto", Network.Name, configured, strings.Join(conflicting, ", ")),
+			})
+		}
+	}
+
+	return messages, nil
+}
+
+// usedSubnets will return the subnets in use by the host and by Docker.
+func usedSubnets(ctx context.Context, cli *client.Client) ([]subnet.Used, error) {
+	// The routing table is best-effort, interface addresses are still used.
+	used, _ := subnet.Host()
+
+	list, err := networks.List(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range list {
+		for _, config := range n.IPAM.Config {
+			if _, network, err := net.ParseCIDR(config.Subnet); err == nil {
+				used = append(used, subnet.Used{Network: network, Source: fmt.Sprintf("docker network %s", n.Name)})
+			}
+		}
+	}
+	return used, nil
+}
+
+// previousSubnet will return the subnet selected on a previous run if it
+// is still free.
+func previousSubnet(previous string, used []subnet.Used) *net.IPNet {
+	_, network, err := net.ParseCIDR(previous)
+	if err != nil {
+		return nil
+	}
+	if len(subnet.Conflicts(network, used)) > 0 {
+		return nil
+	}
+	return network
+}
+
+// setSubnet will replace the IPv4 subnet and gateway of a network.
+func setSubnet(network *networktypes.Inspect, selected *net.IPNet) {
+	config := networktypes.IPAMConfig{
+		Subnet:  selected.String(),
+		Gateway: subnet.Gateway(selected).String(),
+	}
+	for n, existing := range network.IPAM.Config {
+		if ip, _, err := net.ParseCIDR(existing.Subnet); err == nil && ip.To4() != nil {
+			network.IPAM.Config[n] = config
+			return
+		}
+	}
+	network.IPAM.Config = append([]networktypes.IPAMConfig{config}, network.IPAM.Config...)
+}
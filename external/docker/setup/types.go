@@ -2,6 +2,9 @@
 package setup
 
 import (
+	"fmt"
+	"time"
+
 	networktypes "github.com/docker/docker/api/types/network"
 	volumetypes "github.com/docker/docker/api/types/volume"
 	dockerruntime "github.com/pygmystack/pygmy/internal/runtime/docker"
@@ -21,6 +24,15 @@ type Config struct {
 	// TLSCertPath is the path to the TLS certificate to use with the Pygmy haproxy.
 	TLSCertPath string `yaml:"tlsCertPath"`
 
+	// Agent configures how SSH keys are provided to project containers.
+	Agent Agent `yaml:"agent"`
+
+	// Registry configures where the pygmy images are pulled from.
+	Registry Registry `yaml:"registry"`
+
+	// Remote configures how a Docker daemon on another machine is used.
+	Remote Remote `yaml:"remote"`
+
 	// Services is a []model.Service for an index of all Services.
 	Services map[string]dockerruntime.Service `yaml:"services"`
 
@@ -29,12 +41,44 @@ type Config struct {
 	// Networks is for network configuration
 	Networks map[string]networktypes.Inspect `yaml:"networks"`
 
+	// Subnet configures how the subnets of the networks are chosen.
+	Subnet Subnet `yaml:"subnet"`
+
+	// IPv6 enables dual-stack networking, DNS and port bindings.
+	IPv6 bool `yaml:"ipv6"`
+
+	// AutoPorts will remap host ports which are already in use to free
+	// ports instead of refusing to start.
+	AutoPorts bool `yaml:"autoPorts"`
+
 	// NoDefaults will prevent default configuration items.
 	Defaults bool
 
 	// JSONFormat indicates the `status` command should print to stdout in JSON format.
 	JSONFormat bool
 
+	// DryRun indicates a command should print the actions it would perform
+	// instead of performing them.
+	DryRun bool
+
+	// InstanceID identifies the resources created by this installation of
+	// pygmy. It is generated and persisted in the state file when unset.
+	InstanceID string `yaml:"instanceID"`
+
+	// Force will allow clean to remove networks which are still in use by
+	// containers pygmy did not create.
+	Force bool
+
+	// RemoveVolumes will allow clean to remove the volumes pygmy created.
+	RemoveVolumes bool
+
+	// AssumeYes will skip confirmation prompts.
+	AssumeYes bool
+
+	// Passphrase is given to ssh-add for passphrase protected SSH keys
+	// when they are added, instead of it asking for it.
+	Passphrase []byte
+
 	// JSONStatus contains JSON status content.
 	JSONStatus StatusJSON
 
@@ -48,28 +92,208 @@ type Config struct {
 	Volumes map[string]volumetypes.Volume
 }
 
+// StatusSchemaVersion is the version of the StatusJSON schema. It will
+// only change when a field is removed or changes meaning, new fields may
+// be added without changing it.
+const StatusSchemaVersion = 2
+
+// StatusJSON is the output of `pygmy status --json`, which is described
+// by the JSON Schema in StatusSchema.
 type StatusJSON struct {
-	PortAvailability []string                    `json:"port_availability"`
-	Services         map[string]StatusJSONStatus `json:"service_status"`
-	Networks         []string                    `json:"networks"`
-	Resolvers        []string                    `json:"resolvers"`
-	Volumes          []string                    `json:"volumes"`
-	SSHMessages      []string                    `json:"ssh_messages"`
-	URLValidations   []StatusJSONURLValidation   `json:"url_validations"`
+	SchemaVersion  int                         `json:"schema_version"`
+	Checks         []CompatibilityCheck        `json:"checks"`
+	Services       map[string]StatusJSONStatus `json:"service_status"`
+	Networks       []StatusJSONNetwork         `json:"networks"`
+	Resolvers      []StatusJSONResolver        `json:"resolvers"`
+	Volumes        []StatusJSONVolume          `json:"volumes"`
+	Agent          StatusJSONAgent             `json:"agent"`
+	Remote         *StatusJSONRemote           `json:"remote,omitempty"`
+	Keys           []StatusJSONKey             `json:"keys"`
+	URLValidations []StatusJSONURLValidation   `json:"url_validations"`
 }
 
 type StatusJSONURLValidation struct {
-	Endpoint string `json:"endpoint"`
-	Success  bool   `json:"success"`
+	Endpoint          string     `json:"endpoint"`
+	Success           bool       `json:"success"`
+	StatusCode        int        `json:"status_code"`
+	Expected          bool       `json:"expected"`
+	Method            string     `json:"method,omitempty"`
+	LatencySeconds    float64    `json:"latency_seconds"`
+	Redirects         []string   `json:"redirects,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	TLSError          string     `json:"tls_error,omitempty"`
+	CertificateIssuer string     `json:"certificate_issuer,omitempty"`
+	CertificateExpiry *time.Time `json:"certificate_expiry,omitempty"`
 }
 
 type StatusJSONStatus struct {
-	Container string `json:"container"`
-	ImageRef  string `json:"image"`
-	State     bool   `json:"running"`
+	Container    string `json:"container"`
+	ImageRef     string `json:"image"`
+	ImagePresent bool   `json:"image_present"`
+	State        bool   `json:"running"`
+	RestartCount int    `json:"restart_count"`
+}
+
+type StatusJSONNetwork struct {
+	Name       string                       `json:"name"`
+	Created    bool                         `json:"created"`
+	Containers []StatusJSONNetworkContainer `json:"containers"`
+}
+
+type StatusJSONNetworkContainer struct {
+	Name      string `json:"name"`
+	Connected bool   `json:"connected"`
+}
+
+type StatusJSONResolver struct {
+	Name       string `json:"name"`
+	File       string `json:"file"`
+	Configured bool   `json:"configured"`
+	Resolves   bool   `json:"resolves"`
+}
+
+type StatusJSONVolume struct {
+	Name    string `json:"name"`
+	Created bool   `json:"created"`
+}
+
+type StatusJSONAgent struct {
+	Mode   string `json:"mode"`
+	Socket string `json:"socket,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type StatusJSONRemote struct {
+	Host      string `json:"host"`
+	Ports     string `json:"ports,omitempty"`
+	Forwarded bool   `json:"forwarded"`
+	Error     string `json:"error,omitempty"`
+}
+
+type StatusJSONKey struct {
+	Bits        int       `json:"bits"`
+	Fingerprint string    `json:"fingerprint"`
+	Type        string    `json:"type"`
+	Comment     string    `json:"comment"`
+	Path        string    `json:"path,omitempty"`
+	Expires     time.Time `json:"expires,omitzero"`
+}
+
+// Subnet is a struct with the subnet selection options.
+type Subnet struct {
+	// Auto will select a free /24 from Pool for networks which don't
+	// exist yet, instead of using the configured subnet.
+	Auto bool `yaml:"auto"`
+
+	// Pool is the list of ranges a subnet is selected from.
+	Pool []string `yaml:"pool"`
+}
+
+// AgentModeContainer runs an SSH agent in a container, which keys are
+// added to with `pygmy addkey`.
+const AgentModeContainer = "container"
+
+// AgentModeForward proxies the SSH agent running on the host, so private
+// keys are never mounted into a container.
+const AgentModeForward = "forward"
+
+// Agent is a struct with the SSH agent options.
+type Agent struct {
+	// Mode is either AgentModeContainer, the default, or AgentModeForward.
+	Mode string `yaml:"mode"`
+
+	// Socket is the host agent socket to forward. It defaults to
+	// SSH_AUTH_SOCK, or the socket Docker Desktop provides on macOS.
+	Socket string `yaml:"socket"`
+
+	// Image is the image of the container which forwards the socket.
+	Image string `yaml:"image"`
+}
+
+// Registry is a struct with the registry options.
+type Registry struct {
+	// Mirror is a registry mirroring Docker Hub, such as
+	// mirror.example.com:5000, which the pygmystack images are pulled
+	// from instead.
+	Mirror string `yaml:"mirror"`
+}
+
+// Remote is a struct with the options for a Docker daemon on another
+// machine, such as a VM reached over ssh:// or tcp://.
+type Remote struct {
+	// Address is the address the remote daemon publishes ports on. It
+	// defaults to the address of the host the daemon runs on.
+	Address string `yaml:"address"`
+
+	// Forward will forward Ports from this machine to the remote host
+	// over SSH, so the services are reached on 127.0.0.1.
+	Forward bool `yaml:"forward"`
+
+	// Ports are the ports which are forwarded, as "port" or
+	// "local:remote". They default to 80, 443 and 1025.
+	Ports []string `yaml:"ports"`
+
+	// SSH is the destination ports are forwarded through, such as
+	// user@vm. It defaults to the host of an ssh:// daemon.
+	SSH string `yaml:"ssh"`
+
+	// Host is the address of the remote host, which is set by Setup when
+	// the daemon runs on another machine.
+	Host string
+}
+
+// Enabled will report if the daemon runs on another machine.
+func (r Remote) Enabled() bool {
+	return r.Host != ""
+}
+
+// Forwarded will report if the ports of a remote daemon are forwarded to
+// this machine.
+func (r Remote) Forwarded() bool {
+	return r.Enabled() && r.Forward
+}
+
+// Answer will return the address the domain resolves to: the remote host,
+// unless its ports are forwarded to this machine.
+func (r Remote) Answer() string {
+	if !r.Enabled() || r.Forward {
+		return "127.0.0.1"
+	}
+	return r.Host
+}
+
+// Forwarded will report if the host SSH agent is forwarded.
+func (a Agent) Forwarded() bool {
+	return a.Mode == AgentModeForward
 }
 
 // Key is a struct with SSH key details.
 type Key struct {
 	Path string `yaml:"path"`
+
+	// Lifetime is how long the agent keeps the key, such as "8h". The key
+	// is kept until it is removed when unset.
+	Lifetime string `yaml:"lifetime"`
+
+	// Confirm will make the agent ask for confirmation each time the key
+	// is used, which needs an askpass program in the agent container.
+	Confirm bool `yaml:"confirm"`
+
+	// Comment replaces the comment of the key in the agent.
+	Comment string `yaml:"comment"`
+}
+
+// LifetimeDuration will parse Lifetime, which is zero when unset.
+func (k Key) LifetimeDuration() (time.Duration, error) {
+	if k.Lifetime == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(k.Lifetime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lifetime %q for SSH key %v: %w", k.Lifetime, k.Path, err)
+	}
+	if d < time.Second {
+		return 0, fmt.Errorf("invalid lifetime %q for SSH key %v: it must be at least one second", k.Lifetime, k.Path)
+	}
+	return d, nil
 }
@@ -4,14 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	aur "github.com/logrusorgru/aurora"
-	"golang.org/x/term"
 
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
@@ -26,16 +23,8 @@ func (Service *Service) Setup(ctx context.Context, cli *client.Client) error {
 		return fmt.Errorf("image reference is nil value")
 	}
 
-	found := false
-	imgs, _ := images.List(ctx, cli)
-	for _, image := range imgs {
-		if strings.Contains(fmt.Sprint(image.RepoTags), Service.Config.Image) {
-			found = true
-		}
-	}
-
-	if !found {
-		if msg, err := images.Pull(ctx, cli, Service.Config.Image); err != nil {
+	if !Service.ImagePresent(ctx, cli) {
+		if msg, err := images.PullPlatform(ctx, cli, Service.Config.Image, Service.Config.Labels[containers.PlatformLabel]); err != nil {
 			return err
 		} else if strings.Contains(msg, "already up to date") {
 			return errors.New(msg)
@@ -47,6 +36,30 @@ func (Service *Service) Setup(ctx context.Context, cli *client.Client) error {
 	return nil
 }
 
+// ImagePresent will check if the Service's image reference is available
+// in the daemon.
+func (Service *Service) ImagePresent(ctx context.Context, cli *client.Client) bool {
+	return images.Present(ctx, cli, Service.Config.Image)
+}
+
+// Exists will check if the container has been created, regardless of
+// whether it is running.
+func (Service *Service) Exists(ctx context.Context, cli *client.Client) bool {
+	name, err := Service.GetFieldString(ctx, cli, "name")
+	if err != nil || name == "" {
+		return false
+	}
+	c, _ := containers.List(ctx, cli)
+	for _, cn := range c {
+		for _, n := range cn.Names {
+			if strings.TrimPrefix(n, "/") == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Start will perform a series of checks to see if the container starting
 // is supposed be removed before-hand and will check to see if the
 // container is running before it is actually started.
@@ -331,68 +344,16 @@ func (Service *Service) DockerRun(ctx context.Context, cli *client.Client) error
 
 }
 
-// DockerRunInteractive will start an interactive container.
+// DockerRunInteractive will start an interactive container with the
+// terminal attached, and wait for it to exit. A non-zero exit status is
+// returned as a containers.ExitError.
 func (Service *Service) DockerRunInteractive(ctx context.Context, cli *client.Client) error {
 	name, e := Service.GetFieldString(ctx, cli, "name")
 	if e != nil {
 		return fmt.Errorf("container config is missing label for name")
 	}
 
-	waiter, err := containers.Attach(ctx, cli, name, container.AttachOptions{
-		Stderr: true,
-		Stdout: true,
-		Stdin:  true,
-		Stream: true,
-	})
-	if err != nil {
-		return err
-	}
-
-	// Connect the stdin/stdout/stderr streams to the container.
-	go func() {
-		if _, err := io.Copy(os.Stdout, waiter.Reader); err != nil {
-			panic(fmt.Sprintf("Error streaming Stdout: %s", err))
-		}
-	}()
-
-	go func() {
-		if _, err := io.Copy(os.Stderr, waiter.Reader); err != nil {
-			panic(fmt.Sprintf("Error streaming Stderr: %s", err))
-		}
-	}()
-
-	go func() {
-		if _, err := io.Copy(waiter.Conn, os.Stdin); err != nil {
-			panic(fmt.Sprintf("Error streaming Stdin: %s", err))
-		}
-	}()
-
-	if err := containers.Start(ctx, cli, name, container.StartOptions{}); err != nil {
-		return err
-	}
-
-	// Manipulate the terminal raw mode to support passing password prompts.
-	fd := int(os.Stdin.Fd())
-	var oldState *term.State
-	if term.IsTerminal(fd) {
-		oldState, err = term.MakeRaw(fd)
-		if err != nil {
-			return err
-		}
-
-		defer func() {
-			if err := term.Restore(fd, oldState); err != nil {
-				panic(fmt.Sprintf("Error restoring terminal: %s", err))
-			}
-		}()
-	}
-
-	if err := containers.Wait(ctx, cli, name, container.WaitConditionNotRunning); err != nil {
-		return err
-	}
-
-	return nil
-
+	return containers.RunAttached(ctx, cli, name, containers.StandardStreams())
 }
 
 // DockerCreate will setup and run a given container.
@@ -2,25 +2,27 @@ package internals
 
 import (
 	"context"
-	"os"
 
 	"github.com/docker/docker/client"
 
 	containercontext "github.com/pygmystack/pygmy/internal/runtime/docker/internals/context"
 )
 
+// NewClient will connect to the Docker daemon of the selected Docker
+// context, see containercontext.Resolve.
 func NewClient() (*client.Client, context.Context, error) {
 	ctx := context.Background()
-	clientOpts := []client.Opt{
-		client.WithAPIVersionNegotiation(),
+	endpoint, err := containercontext.Resolve()
+	if err != nil {
+		return nil, nil, err
 	}
-	if os.Getenv("DOCKER_HOST") != "" {
-		clientOpts = append(clientOpts, client.FromEnv)
-	} else if currentDockerHost, err := containercontext.CurrentDockerHost(); err != nil {
+	endpointOpts, err := endpoint.ClientOpts()
+	if err != nil {
 		return nil, nil, err
-	} else if currentDockerHost != "" {
-		clientOpts = append(clientOpts, client.WithHost(currentDockerHost))
 	}
+	clientOpts := append([]client.Opt{
+		client.WithAPIVersionNegotiation(),
+	}, endpointOpts...)
 	cli, err := client.NewClientWithOpts(clientOpts...)
 	if err != nil {
 		return nil, nil, err
@@ -0,0 +1,173 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"golang.org/x/term"
+)
+
+// Streams are the standard streams connected to an attached container or
+// exec session.
+type Streams struct {
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+}
+
+// StandardStreams will return the streams of the pygmy process.
+func StandardStreams() Streams {
+	return Streams{In: os.Stdin, Out: os.Stdout, Err: os.Stderr}
+}
+
+// ExitError is returned when an attached process exits with a non-zero
+// status.
+type ExitError struct {
+	Code int
+}
+
+// Error will describe the exit status.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exited with status %d", e.Code)
+}
+
+// ExitCode will return the exit status carried by err: zero for nil, the
+// status of an ExitError and one for any other error.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+	return 1
+}
+
+// RunAttached will start a created container with the streams attached,
+// and wait for it to exit. With a TTY the terminal is put in raw mode and
+// its size is kept in sync with the container, otherwise the output is
+// separated into stdout and stderr. Without a TTY to forward it to, Ctrl-C
+// kills the container. A non-zero exit status returns an ExitError.
+func RunAttached(ctx context.Context, cli *client.Client, id string, streams Streams) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	inspect, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	tty := inspect.Config.Tty
+
+	resp, err := cli.ContainerAttach(ctx, id, containertypes.AttachOptions{
+		Stream: true,
+		Stdin:  inspect.Config.OpenStdin,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	// Wait before starting, so a container which exits straight away
+	// isn't missed.
+	statusCh, errCh := cli.ContainerWait(ctx, id, containertypes.WaitConditionNextExit)
+	if err := cli.ContainerStart(ctx, id, containertypes.StartOptions{}); err != nil {
+		return err
+	}
+
+	restore := rawTerminal(streams.In, tty)
+	defer restore()
+	if tty {
+		go monitorSize(ctx, streams.Out, func(height, width uint) error {
+			return cli.ContainerResize(ctx, id, containertypes.ResizeOptions{Height: height, Width: width})
+		})
+	}
+
+	done := Stream(resp, streams, tty)
+
+	select {
+	case status := <-statusCh:
+		// The output may still be buffered after the container exits.
+		<-done
+		if status.Error != nil {
+			return fmt.Errorf("%s", status.Error.Message)
+		}
+		if status.StatusCode != 0 {
+			return &ExitError{Code: int(status.StatusCode)}
+		}
+		return nil
+	case err := <-errCh:
+		if ctx.Err() != nil {
+			// ctx is done, so the container is killed without it.
+			_ = cli.ContainerKill(context.Background(), id, "")
+			return ctx.Err()
+		}
+		return err
+	}
+}
+
+// Stream will copy streams.In to the hijacked connection, and the output
+// of the connection to streams.Out and streams.Err, which is multiplexed
+// unless the process has a TTY. The returned channel receives the result
+// of copying the output once it ends.
+func Stream(resp types.HijackedResponse, streams Streams, tty bool) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		if tty {
+			_, err = io.Copy(streams.Out, resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(streams.Out, streams.Err, resp.Reader)
+		}
+		done <- err
+	}()
+
+	if streams.In != nil {
+		go func() {
+			// Reading stdin may block until the process exits, so this
+			// isn't waited for.
+			_, _ = io.Copy(resp.Conn, streams.In)
+			_ = resp.CloseWrite()
+		}()
+	}
+
+	return done
+}
+
+// rawTerminal will put the input in raw mode when it is a terminal and the
+// process has a TTY, so that keys such as Ctrl-C and passphrase prompts
+// are handled by the process. The returned function restores it.
+func rawTerminal(in io.Reader, tty bool) func() {
+	file, ok := in.(*os.File)
+	if !ok || !tty || !term.IsTerminal(int(file.Fd())) {
+		return func() {}
+	}
+	state, err := term.MakeRaw(int(file.Fd()))
+	if err != nil {
+		return func() {}
+	}
+	return func() { _ = term.Restore(int(file.Fd()), state) }
+}
+
+// terminalSize will return the size of out when it is a terminal.
+func terminalSize(out io.Writer) (uint, uint, bool) {
+	file, ok := out.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return 0, 0, false
+	}
+	width, height, err := term.GetSize(int(file.Fd()))
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint(height), uint(width), true
+}
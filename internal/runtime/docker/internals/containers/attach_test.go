@@ -0,0 +1,67 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+)
+
+// hijacked will return a connection which behaves like an attached
+// container: it writes output to the client and records the input.
+func hijacked(t *testing.T, output []byte) (types.HijackedResponse, *bytes.Buffer, chan struct{}) {
+	client, server := net.Pipe()
+	input := &bytes.Buffer{}
+	received := make(chan struct{})
+	go func() {
+		_, _ = server.Write(output)
+		_ = server.Close()
+	}()
+	go func() {
+		_, _ = io.Copy(input, server)
+		close(received)
+	}()
+	t.Cleanup(func() { _ = client.Close() })
+	return types.NewHijackedResponse(client, ""), input, received
+}
+
+func TestStreamDemultiplexes(t *testing.T) {
+	var output bytes.Buffer
+	_, _ = stdcopy.NewStdWriter(&output, stdcopy.Stdout).Write([]byte("out\n"))
+	_, _ = stdcopy.NewStdWriter(&output, stdcopy.Stderr).Write([]byte("err\n"))
+
+	resp, _, _ := hijacked(t, output.Bytes())
+	resp.Reader = bufio.NewReader(resp.Conn)
+	var stdout, stderr bytes.Buffer
+	err := <-Stream(resp, Streams{Out: &stdout, Err: &stderr}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+func TestStreamTTY(t *testing.T) {
+	resp, _, _ := hijacked(t, []byte("Enter passphrase: "))
+	resp.Reader = bufio.NewReader(resp.Conn)
+	var stdout, stderr bytes.Buffer
+	err := <-Stream(resp, Streams{In: strings.NewReader("secret\n"), Out: &stdout, Err: &stderr}, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Enter passphrase: ", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, 3, ExitCode(&ExitError{Code: 3}))
+	assert.Equal(t, 3, ExitCode(fmt.Errorf("wrapped: %w", &ExitError{Code: 3})))
+	assert.Equal(t, 1, ExitCode(errors.New("failed")))
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"runtime"
 	"strings"
 
 	"github.com/containerd/platforms"
@@ -13,6 +12,7 @@ import (
 	containertypes "github.com/docker/docker/api/types/container"
 	networktypes "github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -73,6 +73,49 @@ func Exec(ctx context.Context, client *client.Client, container string, command
 
 }
 
+// ExecResult is the output and exit code of a command run by ExecCommand.
+type ExecResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// ExecCommand will run a command in a running container and wait for it
+// to exit. Unlike Exec, the arguments are passed as given and the output
+// is separated into stdout and stderr.
+func ExecCommand(ctx context.Context, client *client.Client, container string, cmd []string) (ExecResult, error) {
+	rst, err := client.ContainerExecCreate(ctx, container, containertypes.ExecOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return ExecResult{}, err
+	}
+
+	response, err := client.ContainerExecAttach(ctx, rst.ID, containertypes.ExecAttachOptions{})
+	if err != nil {
+		return ExecResult{}, err
+	}
+	defer response.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, response.Reader); err != nil {
+		return ExecResult{}, err
+	}
+
+	inspect, err := client.ContainerExecInspect(ctx, rst.ID)
+	if err != nil {
+		return ExecResult{}, err
+	}
+
+	return ExecResult{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: inspect.ExitCode,
+	}, nil
+}
+
 // List will return a slice of containers
 func List(ctx context.Context, client *client.Client) ([]containertypes.Summary, error) {
 	containers, err := client.ContainerList(ctx, containertypes.ListOptions{
@@ -85,13 +128,22 @@ func List(ctx context.Context, client *client.Client) ([]containertypes.Summary,
 	return containers, nil
 }
 
+// PlatformLabel is the label which selects the platform a container runs
+// as, such as linux/amd64. Without it the daemon uses its native platform.
+const PlatformLabel = "pygmy.platform"
+
 // Create will create a container, but will not run it.
 func Create(ctx context.Context, client *client.Client, ID string, config containertypes.Config, hostconfig containertypes.HostConfig, networkconfig networktypes.NetworkingConfig) (containertypes.CreateResponse, error) {
-	platform := platforms.Normalize(v1.Platform{
-		Architecture: runtime.GOARCH,
-		OS:           "linux",
-	})
-	resp, err := client.ContainerCreate(ctx, &config, &hostconfig, &networkconfig, &platform, ID)
+	var platform *v1.Platform
+	if label := config.Labels[PlatformLabel]; label != "" {
+		p, err := platforms.Parse(label)
+		if err != nil {
+			return containertypes.CreateResponse{}, fmt.Errorf("invalid platform %q: %w", label, err)
+		}
+		p = platforms.Normalize(p)
+		platform = &p
+	}
+	resp, err := client.ContainerCreate(ctx, &config, &hostconfig, &networkconfig, platform, ID)
 	if err != nil {
 		return containertypes.CreateResponse{}, err
 	}
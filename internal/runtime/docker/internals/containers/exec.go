@@ -0,0 +1,60 @@
+package containers
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// ExecAttached will run a command in a running container with the streams
+// attached, and wait for it to exit. With options.Tty the terminal is put
+// in raw mode and its size is kept in sync with the command. Without a TTY
+// to forward it to, Ctrl-C stops waiting for the command. A non-zero exit
+// status returns an ExitError.
+func ExecAttached(ctx context.Context, cli *client.Client, container string, options containertypes.ExecOptions, streams Streams) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	options.AttachStdin = streams.In != nil
+	options.AttachStdout = true
+	options.AttachStderr = true
+	created, err := cli.ContainerExecCreate(ctx, container, options)
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.ContainerExecAttach(ctx, created.ID, containertypes.ExecAttachOptions{Tty: options.Tty})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	restore := rawTerminal(streams.In, options.Tty)
+	defer restore()
+	if options.Tty {
+		go monitorSize(ctx, streams.Out, func(height, width uint) error {
+			return cli.ContainerExecResize(ctx, created.ID, containertypes.ResizeOptions{Height: height, Width: width})
+		})
+	}
+
+	select {
+	case err := <-Stream(resp, streams, options.Tty):
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.ExitCode != 0 {
+		return &ExitError{Code: inspect.ExitCode}
+	}
+	return nil
+}
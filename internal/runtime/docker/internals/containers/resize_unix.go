@@ -0,0 +1,35 @@
+//go:build !windows
+// +build !windows
+
+package containers
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// monitorSize will call resize with the size of out when it starts and
+// each time the terminal is resized, until ctx is done.
+func monitorSize(ctx context.Context, out io.Writer, resize func(height, width uint) error) {
+	if height, width, ok := terminalSize(out); ok {
+		_ = resize(height, width)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if height, width, ok := terminalSize(out); ok {
+				_ = resize(height, width)
+			}
+		}
+	}
+}
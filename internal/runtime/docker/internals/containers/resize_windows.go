@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package containers
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// monitorSize will call resize with the size of out when it starts and
+// each time the terminal is resized, until ctx is done. Windows has no
+// resize signal, so the size is polled.
+func monitorSize(ctx context.Context, out io.Writer, resize func(height, width uint) error) {
+	var lastHeight, lastWidth uint
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if height, width, ok := terminalSize(out); ok && (height != lastHeight || width != lastWidth) {
+			if resize(height, width) == nil {
+				lastHeight, lastWidth = height, width
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
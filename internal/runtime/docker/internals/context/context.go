@@ -1,22 +1,81 @@
 package context
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
 )
 
+// DefaultContext is the context which uses DOCKER_HOST, or the default
+// socket of the platform.
+const DefaultContext = "default"
+
 type DockerConfig struct {
 	CurrentContext string `json:"currentContext"`
 }
 
 type DockerContextManifest struct {
 	Name      string
+	Metadata  map[string]interface{}
 	Endpoints map[string]struct {
-		Host string
+		Host          string
+		SkipTLSVerify bool
+	}
+}
+
+// TLS are the paths of the TLS material stored with a context. Each path
+// is empty when the context doesn't have that file.
+type TLS struct {
+	CA   string `json:"ca,omitempty"`
+	Cert string `json:"cert,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+// Endpoint is the Docker daemon pygmy talks to, and why it was chosen.
+type Endpoint struct {
+	// Context is the name of the Docker context.
+	Context string `json:"context"`
+	// Source explains how the context was chosen.
+	Source string `json:"source"`
+	// Host is the address of the daemon. It is empty for the default
+	// socket of the platform.
+	Host string `json:"host"`
+	// TLS is the TLS material of the context, if any.
+	TLS *TLS `json:"tls,omitempty"`
+	// SkipTLSVerify disables the verification of the daemon certificate.
+	SkipTLSVerify bool `json:"skip_tls_verify"`
+}
+
+// override is the context selected with the --context flag.
+var override string
+
+// Use will select the context to use, as the --context flag of the Docker
+// CLI does. It takes precedence over DOCKER_HOST and DOCKER_CONTEXT.
+func Use(name string) {
+	override = name
+}
+
+// ConfigDir will return the directory of the Docker CLI configuration,
+// which can be moved with the DOCKER_CONFIG environment variable.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir, nil
 	}
+	return filePathInHomeDir(".docker")
 }
 
 func filePathInHomeDir(elem ...string) (string, error) {
@@ -28,8 +87,18 @@ func filePathInHomeDir(elem ...string) (string, error) {
 	return filepath.Join(append([]string{home}, elem...)...), nil
 }
 
+// filePathInConfigDir will return the path of a file in the Docker CLI
+// configuration directory.
+func filePathInConfigDir(elem ...string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
 func currentContext() (string, error) {
-	configPath, err := filePathInHomeDir(".docker", "config.json")
+	configPath, err := filePathInConfigDir("config.json")
 	if err != nil {
 		return "", err
 	}
@@ -51,14 +120,34 @@ func currentContext() (string, error) {
 	return dockerConfig.CurrentContext, nil
 }
 
-func endpointFromContext(context string) (string, error) {
-	manifestDir, err := filePathInHomeDir(".docker", "contexts", "meta")
+// contextID will return the directory name the Docker CLI stores a
+// context under.
+func contextID(name string) string {
+	digest := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(digest[:])
+}
+
+// loadContext will read the manifest of a context, reporting false when
+// the context doesn't exist.
+func loadContext(context string) (DockerContextManifest, bool, error) {
+	manifestDir, err := filePathInConfigDir("contexts", "meta")
 	if err != nil {
-		return "", err
+		return DockerContextManifest{}, false, err
 	}
 
-	var contextManifest DockerContextManifest
+	// Contexts are stored under the digest of their name, contexts
+	// stored any other way are found by walking the directory.
+	manifest := DockerContextManifest{}
+	if manifestBytes, err := os.ReadFile(filepath.Join(manifestDir, contextID(context), "meta.json")); err == nil {
+		if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+			return DockerContextManifest{}, false, err
+		}
+		if manifest.Name == context {
+			return manifest, true, nil
+		}
+	}
 
+	found := false
 	err = filepath.WalkDir(manifestDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -72,18 +161,24 @@ func endpointFromContext(context string) (string, error) {
 			return err
 		}
 
-		manifest := DockerContextManifest{}
-		err = json.Unmarshal(manifestBytes, &manifest)
+		m := DockerContextManifest{}
+		err = json.Unmarshal(manifestBytes, &m)
 		if err != nil {
 			return err
 		}
 
-		if manifest.Name == context {
-			contextManifest = manifest
+		if m.Name == context {
+			manifest = m
+			found = true
 		}
 		return nil
 	})
 
+	return manifest, found, err
+}
+
+func endpointFromContext(context string) (string, error) {
+	contextManifest, _, err := loadContext(context)
 	if err != nil {
 		return "", err
 	}
@@ -91,18 +186,193 @@ func endpointFromContext(context string) (string, error) {
 	return contextManifest.Endpoints["docker"].Host, nil
 }
 
-func CurrentDockerHost() (string, error) {
-	dockerCurrentContext, err := currentContext()
+// contextTLS will return the TLS material stored with a context, or nil
+// when it has none.
+func contextTLS(context string) (*TLS, error) {
+	dir, err := filePathInConfigDir("contexts", "tls", contextID(context), "docker")
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	material := &TLS{}
+	found := false
+	for name, path := range map[string]*string{"ca.pem": &material.CA, "cert.pem": &material.Cert, "key.pem": &material.Key} {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			*path = file
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return material, nil
+}
+
+// Resolve will determine the daemon to talk to the way the Docker CLI
+// does: the --context flag, then DOCKER_HOST, then DOCKER_CONTEXT, then
+// the current context of the Docker CLI configuration, and otherwise the
+// default context.
+func Resolve() (Endpoint, error) {
+	name, source := "", ""
+	switch {
+	case override != "":
+		name, source = override, "the --context flag"
+	case os.Getenv("DOCKER_HOST") != "":
+		return Endpoint{Context: DefaultContext, Source: "DOCKER_HOST", Host: os.Getenv("DOCKER_HOST")}, nil
+	case os.Getenv("DOCKER_CONTEXT") != "":
+		name, source = os.Getenv("DOCKER_CONTEXT"), "DOCKER_CONTEXT"
+	default:
+		current, err := currentContext()
+		if err != nil {
+			return Endpoint{}, err
+		}
+		configPath, _ := filePathInConfigDir("config.json")
+		name, source = current, fmt.Sprintf("currentContext in %s", configPath)
+	}
+
+	if name == "" {
+		return Endpoint{Context: DefaultContext, Source: "no context is selected", Host: os.Getenv("DOCKER_HOST")}, nil
+	}
+	if name == DefaultContext {
+		return Endpoint{Context: DefaultContext, Source: source, Host: os.Getenv("DOCKER_HOST")}, nil
+	}
+
+	manifest, found, err := loadContext(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Endpoint{}, err
+	}
+	if !found {
+		return Endpoint{}, fmt.Errorf("context %q does not exist, it was selected by %s", name, source)
+	}
+
+	endpoint := Endpoint{
+		Context:       name,
+		Source:        source,
+		Host:          manifest.Endpoints["docker"].Host,
+		SkipTLSVerify: manifest.Endpoints["docker"].SkipTLSVerify,
+	}
+	if endpoint.TLS, err = contextTLS(name); err != nil {
+		return Endpoint{}, err
 	}
+	return endpoint, nil
+}
 
-	currentDockerHost := ""
-	if dockerCurrentContext != "" {
-		currentDockerHost, err = endpointFromContext(dockerCurrentContext)
+// ClientOpts will return the options to connect a Docker client to the
+// endpoint. The default context also honours DOCKER_TLS_VERIFY,
+// DOCKER_CERT_PATH and DOCKER_API_VERSION.
+func (e Endpoint) ClientOpts() ([]client.Opt, error) {
+	if strings.HasPrefix(e.Host, "ssh://") {
+		dialer, err := sshDialer(e.Host)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		var opts []client.Opt
+		if e.Context == DefaultContext {
+			opts = append(opts, client.FromEnv)
+		}
+		// The host is not used, every connection is made with ssh.
+		return append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dialer)), nil
+	}
+	if e.Context == DefaultContext {
+		return []client.Opt{client.FromEnv}, nil
+	}
+	if e.Host == "" {
+		return nil, nil
+	}
+
+	var opts []client.Opt
+	config, err := e.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport:     &http.Transport{TLSClientConfig: config},
+			CheckRedirect: client.CheckRedirect,
+		}))
+	}
+	return append(opts, client.WithHost(e.Host)), nil
+}
+
+// tlsConfig will build the TLS configuration of the endpoint, or return nil
+// when it doesn't use TLS.
+func (e Endpoint) tlsConfig() (*tls.Config, error) {
+	if e.TLS == nil && !e.SkipTLSVerify {
+		return nil, nil
+	}
+	options := tlsconfig.Options{InsecureSkipVerify: e.SkipTLSVerify}
+	if e.TLS != nil {
+		options.CAFile = e.TLS.CA
+		options.CertFile = e.TLS.Cert
+		options.KeyFile = e.TLS.Key
+	}
+	config, err := tlsconfig.Client(options)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the TLS material of context %s: %w", e.Context, err)
+	}
+	return config, nil
+}
+
+// Remote will report if the daemon runs on another machine: it is reached
+// over ssh://, or over TCP on an address which isn't a loopback address.
+func (e Endpoint) Remote() bool {
+	u, err := url.Parse(e.Host)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "ssh":
+		return true
+	case "tcp", "http", "https":
+		return !loopback(u.Hostname())
+	}
+	return false
+}
+
+// RemoteIP will return the address of the machine a remote daemon runs
+// on, which its published ports are reached on. The host of an ssh://
+// daemon is looked up in the SSH configuration, so aliases are followed.
+func (e Endpoint) RemoteIP(ctx context.Context) (string, error) {
+	u, err := url.Parse(e.Host)
+	if err != nil {
+		return "", err
+	}
+	host := u.Hostname()
+	if u.Scheme == "ssh" {
+		if args, err := SSHArgs(e.Host); err == nil {
+			if name, err := sshHostName(args); err == nil {
+				host = name
+			}
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	// IPv4 is preferred, as the services are published on it by default.
+	for _, network := range []string{"ip4", "ip"} {
+		ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+		if err == nil && len(ips) > 0 {
+			return ips[0].String(), nil
 		}
 	}
-	return currentDockerHost, nil
+	return "", fmt.Errorf("could not find the address of the Docker host %s", host)
+}
+
+// loopback will report if a host name refers to this machine.
+func loopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func CurrentDockerHost() (string, error) {
+	endpoint, err := Resolve()
+	if err != nil {
+		return "", err
+	}
+	return endpoint.Host, nil
 }
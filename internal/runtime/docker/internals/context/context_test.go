@@ -1,9 +1,20 @@
 package context
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/client"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,3 +46,180 @@ func TestEndpointFromContext(t *testing.T) {
 		assert.Nil(t, err)
 	}
 }
+
+// writeContext will store a context the way the Docker CLI does.
+func writeContext(t *testing.T, dir string, name string, host string, skipTLSVerify bool) {
+	meta := filepath.Join(dir, "contexts", "meta", contextID(name))
+	assert.NoError(t, os.MkdirAll(meta, 0700))
+	manifest := fmt.Sprintf(`{"Name":%q,"Metadata":{},"Endpoints":{"docker":{"Host":%q,"SkipTLSVerify":%v}}}`, name, host, skipTLSVerify)
+	assert.NoError(t, os.WriteFile(filepath.Join(meta, "meta.json"), []byte(manifest), 0600))
+}
+
+// writeTLS will store a self-signed certificate as the TLS material of a
+// context.
+func writeTLS(t *testing.T, dir string, name string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pygmy"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	tlsDir := filepath.Join(dir, "contexts", "tls", contextID(name), "docker")
+	assert.NoError(t, os.MkdirAll(tlsDir, 0700))
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	assert.NoError(t, os.WriteFile(filepath.Join(tlsDir, "ca.pem"), certPEM, 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(tlsDir, "cert.pem"), certPEM, 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(tlsDir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	return tlsDir
+}
+
+// TestResolve will test the order contexts are selected in.
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	defer Use("")
+
+	writeContext(t, dir, "current", "unix:///current.sock", false)
+	writeContext(t, dir, "env", "tcp://env.example.com:2376", false)
+	writeContext(t, dir, "flag", "tcp://flag.example.com:2376", true)
+
+	endpoint, err := Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultContext, endpoint.Context)
+	assert.Equal(t, "no context is selected", endpoint.Source)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"currentContext":"current"}`), 0600))
+	endpoint, err = Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, "current", endpoint.Context)
+	assert.Equal(t, "unix:///current.sock", endpoint.Host)
+	assert.Contains(t, endpoint.Source, "currentContext")
+
+	t.Setenv("DOCKER_CONTEXT", "env")
+	endpoint, err = Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, "env", endpoint.Context)
+	assert.Equal(t, "DOCKER_CONTEXT", endpoint.Source)
+
+	t.Setenv("DOCKER_HOST", "tcp://host.example.com:2375")
+	endpoint, err = Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultContext, endpoint.Context)
+	assert.Equal(t, "tcp://host.example.com:2375", endpoint.Host)
+	assert.Equal(t, "DOCKER_HOST", endpoint.Source)
+
+	Use("flag")
+	endpoint, err = Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", endpoint.Context)
+	assert.Equal(t, "tcp://flag.example.com:2376", endpoint.Host)
+	assert.True(t, endpoint.SkipTLSVerify)
+	assert.Nil(t, endpoint.TLS)
+
+	Use("default")
+	endpoint, err = Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultContext, endpoint.Context)
+	assert.Equal(t, "tcp://host.example.com:2375", endpoint.Host)
+
+	Use("missing")
+	_, err = Resolve()
+	assert.ErrorContains(t, err, `context "missing" does not exist`)
+}
+
+// TestClientOpts will test connecting to contexts with TLS material.
+func TestClientOpts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "remote")
+	defer Use("")
+
+	writeContext(t, dir, "remote", "tcp://remote.example.com:2376", false)
+	tlsDir := writeTLS(t, dir, "remote")
+
+	endpoint, err := Resolve()
+	assert.NoError(t, err)
+	assert.Equal(t, &TLS{
+		CA:   filepath.Join(tlsDir, "ca.pem"),
+		Cert: filepath.Join(tlsDir, "cert.pem"),
+		Key:  filepath.Join(tlsDir, "key.pem"),
+	}, endpoint.TLS)
+
+	opts, err := endpoint.ClientOpts()
+	assert.NoError(t, err)
+	cli, err := client.NewClientWithOpts(opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://remote.example.com:2376", cli.DaemonHost())
+	config, err := endpoint.tlsConfig()
+	assert.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.NotNil(t, config.RootCAs)
+	assert.False(t, config.InsecureSkipVerify)
+
+	assert.NoError(t, os.WriteFile(endpoint.TLS.Key, []byte("invalid"), 0600))
+	_, err = endpoint.ClientOpts()
+	assert.Error(t, err)
+
+	opts, err = Endpoint{Context: DefaultContext}.ClientOpts()
+	assert.NoError(t, err)
+	assert.Len(t, opts, 1)
+}
+
+// TestRemote will test detecting daemons on other machines.
+func TestRemote(t *testing.T) {
+	for host, remote := range map[string]bool{
+		"":                            false,
+		"unix:///var/run/docker.sock": false,
+		"npipe:////./pipe/docker":     false,
+		"tcp://127.0.0.1:2375":        false,
+		"tcp://localhost:2375":        false,
+		"tcp://[::1]:2375":            false,
+		"tcp://192.168.64.2:2376":     true,
+		"ssh://docker@vm":             true,
+	} {
+		assert.Equal(t, remote, Endpoint{Host: host}.Remote(), host)
+	}
+
+	ip, err := Endpoint{Host: "tcp://192.168.64.2:2376"}.RemoteIP(t.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.64.2", ip)
+}
+
+// TestSSHArgs will test the ssh arguments for ssh:// hosts.
+func TestSSHArgs(t *testing.T) {
+	args, err := SSHArgs("ssh://docker@vm.example.com:2222")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-l", "docker", "-p", "2222", "vm.example.com"}, args)
+
+	args, err = SSHArgs("ssh://vm")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vm"}, args)
+
+	args, err = SSHArgs("docker@vm")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docker@vm"}, args)
+
+	for _, invalid := range []string{"", "-oProxyCommand=true", "ssh://-oProxyCommand=true", "ssh://vm/path", "ssh://docker:secret@vm"} {
+		_, err = SSHArgs(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	opts, err := Endpoint{Context: DefaultContext, Host: "ssh://docker@vm"}.ClientOpts()
+	assert.NoError(t, err)
+	cli, err := client.NewClientWithOpts(opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://docker.example.com", cli.DaemonHost())
+}
@@ -0,0 +1,156 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSHArgs will return the arguments which make ssh connect to a
+// destination, which is either an ssh:// URL as used by DOCKER_HOST, or a
+// destination ssh accepts such as user@host. Options must be given to ssh
+// before these arguments.
+func SSHArgs(destination string) ([]string, error) {
+	if !strings.HasPrefix(destination, "ssh://") {
+		if destination == "" || strings.HasPrefix(destination, "-") {
+			return nil, fmt.Errorf("invalid ssh destination %q", destination)
+		}
+		return []string{destination}, nil
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh host %q: %w", destination, err)
+	}
+	if u.Hostname() == "" || strings.HasPrefix(u.Hostname(), "-") {
+		return nil, fmt.Errorf("invalid ssh host %q: no host name", destination)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("invalid ssh host %q: paths are not supported", destination)
+	}
+	if _, ok := u.User.Password(); ok {
+		return nil, fmt.Errorf("invalid ssh host %q: passwords are not supported", destination)
+	}
+
+	var args []string
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	return append(args, u.Hostname()), nil
+}
+
+// sshHostName will return the host name ssh connects to for the given
+// arguments, which applies the aliases in the SSH configuration.
+func sshHostName(args []string) (string, error) {
+	output, err := exec.Command("ssh", append([]string{"-G"}, args...)...).Output()
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), " "); ok && key == "hostname" {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("ssh did not report a host name")
+}
+
+// sshDialer will connect to the daemon of an ssh:// host by running
+// docker system dial-stdio on it, as the Docker CLI does.
+func sshDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	args, err := SSHArgs(host)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		cmd := exec.Command("ssh", append(append([]string{"-o", "ConnectTimeout=30", "-T"}, args...), "docker", "system", "dial-stdio")...)
+		return commandConn(cmd)
+	}, nil
+}
+
+// commandConnection is a connection to the standard input and output of a
+// command.
+type commandConnection struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+	once   sync.Once
+}
+
+// commandConn will start cmd and return a connection to it.
+func commandConn(cmd *exec.Cmd) (net.Conn, error) {
+	c := &commandConnection{cmd: cmd}
+	var err error
+	if c.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if c.stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *commandConnection) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err == io.EOF && c.stderr.Len() > 0 {
+		return n, fmt.Errorf("ssh connection closed: %s", strings.TrimSpace(c.stderr.String()))
+	}
+	return n, err
+}
+
+func (c *commandConnection) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// CloseWrite will close the input of the command, which is used when the
+// connection is hijacked.
+func (c *commandConnection) CloseWrite() error {
+	return c.stdin.Close()
+}
+
+func (c *commandConnection) Close() error {
+	c.once.Do(func() {
+		_ = c.stdin.Close()
+		if c.cmd.Process != nil {
+			_ = c.cmd.Process.Kill()
+		}
+		_ = c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConnection) LocalAddr() net.Addr {
+	return commandAddr{}
+}
+
+func (c *commandConnection) RemoteAddr() net.Addr {
+	return commandAddr{}
+}
+
+// Deadlines are not supported by the pipes of a command, the daemon
+// requests are bound by their context instead.
+func (c *commandConnection) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConnection) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConnection) SetWriteDeadline(t time.Time) error { return nil }
+
+// commandAddr is the address of a commandConnection.
+type commandAddr struct{}
+
+func (commandAddr) Network() string { return "command" }
+func (commandAddr) String() string  { return "command" }
@@ -0,0 +1,38 @@
+package images
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// Save will write images to w as a tar archive, as `docker save` does.
+func Save(ctx context.Context, cli *client.Client, images []string, w io.Writer) error {
+	data, err := cli.ImageSave(ctx, images)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = data.Close() }()
+	_, err = io.Copy(w, data)
+	return err
+}
+
+// Load will import the images in a tar archive written by Save, which may
+// be gzip compressed, as `docker load` does. The progress is written to
+// out.
+func Load(ctx context.Context, cli *client.Client, r io.Reader, out io.Writer) error {
+	resp, err := cli.ImageLoad(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if !resp.JSON {
+		_, err = io.Copy(out, resp.Body)
+		return err
+	}
+	fd, terminal := terminalFd(out)
+	return jsonmessage.DisplayJSONMessagesStream(resp.Body, out, fd, terminal, nil)
+}
@@ -0,0 +1,174 @@
+package images
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/mitchellh/go-homedir"
+)
+
+// dockerHubServer is the server address the Docker CLI stores the
+// credentials of Docker Hub under.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// dockerConfig is the part of the Docker CLI configuration which holds
+// registry credentials.
+type dockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+}
+
+// dockerAuth is an entry in the auths section of the Docker CLI
+// configuration.
+type dockerAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// ConfigPath will return the location of the Docker CLI configuration,
+// which can be moved with the DOCKER_CONFIG environment variable.
+func ConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, _ := homedir.Dir()
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// serverAddress will return the address credentials for a registry are
+// stored under.
+func serverAddress(domain string) string {
+	if domain == "docker.io" || domain == "index.docker.io" {
+		return dockerHubServer
+	}
+	return domain
+}
+
+// serverHost will reduce an address in the auths section, which may be a
+// URL, to the registry it is for.
+func serverHost(address string) string {
+	address = strings.TrimPrefix(address, "https://")
+	address = strings.TrimPrefix(address, "http://")
+	address, _, _ = strings.Cut(address, "/")
+	if address == "index.docker.io" || address == "registry-1.docker.io" {
+		return "docker.io"
+	}
+	return address
+}
+
+// Credentials will look up the credentials for the registry of an image
+// the way `docker login` stores them: with the credential helper for the
+// registry, the default credential store, or the auths section of the
+// Docker CLI configuration. Having no credentials is not an error, the
+// image is then pulled anonymously.
+func Credentials(image string) (registry.AuthConfig, error) {
+	domain := Registry(image)
+	if domain == "" {
+		return registry.AuthConfig{}, nil
+	}
+	server := serverAddress(domain)
+
+	data, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registry.AuthConfig{}, nil
+		}
+		return registry.AuthConfig{}, err
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("could not read %s: %w", ConfigPath(), err)
+	}
+
+	helper := config.CredsStore
+	if h, ok := config.CredHelpers[domain]; ok {
+		helper = h
+	}
+	if helper != "" {
+		auth, found, err := helperCredentials(helper, server)
+		if err != nil || found {
+			return auth, err
+		}
+	}
+
+	for address, entry := range config.Auths {
+		if serverHost(address) != serverHost(server) {
+			continue
+		}
+		auth := registry.AuthConfig{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+			ServerAddress: server,
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return registry.AuthConfig{}, fmt.Errorf("invalid credentials for %s in %s: %w", address, ConfigPath(), err)
+			}
+			auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		return auth, nil
+	}
+
+	return registry.AuthConfig{}, nil
+}
+
+// helperCredentials will ask the docker-credential-<helper> program for
+// the credentials of server, reporting false when it has none.
+func helperCredentials(helper string, server string) (registry.AuthConfig, bool, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(output, "credentials not found") {
+			return registry.AuthConfig{}, false, nil
+		}
+		return registry.AuthConfig{}, false, fmt.Errorf("credential helper docker-credential-%s failed: %v %s", helper, err, output)
+	}
+
+	var credentials struct {
+		ServerURL string
+		Username  string
+		Secret    string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &credentials); err != nil {
+		return registry.AuthConfig{}, false, fmt.Errorf("credential helper docker-credential-%s returned invalid output: %w", helper, err)
+	}
+
+	auth := registry.AuthConfig{ServerAddress: server}
+	if credentials.Username == "<token>" {
+		// Helpers return identity tokens with this placeholder username.
+		auth.IdentityToken = credentials.Secret
+	} else {
+		auth.Username = credentials.Username
+		auth.Password = credentials.Secret
+	}
+	return auth, true, nil
+}
+
+// RegistryAuth will return the credentials for an image encoded for the
+// Docker API, or an empty string to pull anonymously.
+func RegistryAuth(image string) (string, error) {
+	auth, err := Credentials(image)
+	if err != nil {
+		return "", err
+	}
+	if auth == (registry.AuthConfig{}) {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(auth)
+}
@@ -0,0 +1,125 @@
+package images
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// writeConfig will write a Docker CLI configuration and point
+// DOCKER_CONFIG at it.
+func writeConfig(t *testing.T, config string) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	So(os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600), ShouldBeNil)
+}
+
+// TestCredentials will test reading credentials from the auths section.
+func TestCredentials(t *testing.T) {
+	Convey("Images: Credential tests...", t, func() {
+		Convey("without a configuration", func() {
+			t.Setenv("DOCKER_CONFIG", t.TempDir())
+			auth, err := Credentials("nginx")
+			So(err, ShouldBeNil)
+			So(auth, ShouldResemble, registry.AuthConfig{})
+		})
+
+		Convey("from the auths section", func() {
+			encoded := base64.StdEncoding.EncodeToString([]byte("user:pass:word"))
+			writeConfig(t, `{"auths": {
+				"https://index.docker.io/v1/": {"auth": "`+encoded+`"},
+				"localhost:5000": {"identitytoken": "token"},
+				"https://mirror.example.com/v2/": {"username": "mirror", "password": "secret"}
+			}}`)
+
+			tests := []struct {
+				image string
+				auth  registry.AuthConfig
+			}{
+				{"nginx", registry.AuthConfig{Username: "user", Password: "pass:word", ServerAddress: dockerHubServer}},
+				{"docker.io/pygmystack/haproxy:latest", registry.AuthConfig{Username: "user", Password: "pass:word", ServerAddress: dockerHubServer}},
+				{"localhost:5000/img", registry.AuthConfig{IdentityToken: "token", ServerAddress: "localhost:5000"}},
+				{"mirror.example.com/pygmystack/haproxy", registry.AuthConfig{Username: "mirror", Password: "secret", ServerAddress: "mirror.example.com"}},
+				{"quay.io/pygmystack/pygmy", registry.AuthConfig{}},
+			}
+			for _, test := range tests {
+				auth, err := Credentials(test.image)
+				So(err, ShouldBeNil)
+				So(auth, ShouldResemble, test.auth)
+			}
+
+			encodedAuth, err := RegistryAuth("quay.io/pygmystack/pygmy")
+			So(err, ShouldBeNil)
+			So(encodedAuth, ShouldBeEmpty)
+			encodedAuth, err = RegistryAuth("nginx")
+			So(err, ShouldBeNil)
+			decoded, err := registry.DecodeAuthConfig(encodedAuth)
+			So(err, ShouldBeNil)
+			So(decoded.Username, ShouldEqual, "user")
+		})
+
+		Convey("with an invalid auth", func() {
+			writeConfig(t, `{"auths": {"https://index.docker.io/v1/": {"auth": "%%%"}}}`)
+			_, err := Credentials("nginx")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("with a corrupt configuration", func() {
+			writeConfig(t, `{`)
+			_, err := Credentials("nginx")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+// TestCredentialHelpers will test reading credentials from credential
+// helpers, which take precedence over the auths section.
+func TestCredentialHelpers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake credential helpers are shell scripts")
+	}
+
+	Convey("Images: Credential helper tests...", t, func() {
+		bin := t.TempDir()
+		t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+		helpers := map[string]string{
+			"docker-credential-store":  `read server; echo "{\"ServerURL\":\"$server\",\"Username\":\"store-$server\",\"Secret\":\"s\"}"`,
+			"docker-credential-token":  `read server; echo '{"ServerURL":"ghcr.io","Username":"<token>","Secret":"t"}'`,
+			"docker-credential-empty":  `echo "credentials not found in native keychain"; exit 1`,
+			"docker-credential-broken": `echo "keychain locked" >&2; exit 1`,
+		}
+		for name, script := range helpers {
+			So(os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+script+"\n"), 0700), ShouldBeNil)
+		}
+
+		writeConfig(t, `{
+			"credsStore": "store",
+			"credHelpers": {"ghcr.io": "token", "quay.io": "empty", "registry.example.com": "broken"},
+			"auths": {"quay.io": {"username": "quay", "password": "p"}}
+		}`)
+
+		tests := []struct {
+			image string
+			auth  registry.AuthConfig
+		}{
+			{"nginx", registry.AuthConfig{Username: "store-" + dockerHubServer, Password: "s", ServerAddress: dockerHubServer}},
+			{"localhost:5000/img", registry.AuthConfig{Username: "store-localhost:5000", Password: "s", ServerAddress: "localhost:5000"}},
+			{"ghcr.io/org/team/img", registry.AuthConfig{IdentityToken: "t", ServerAddress: "ghcr.io"}},
+			{"quay.io/pygmystack/pygmy", registry.AuthConfig{Username: "quay", Password: "p", ServerAddress: "quay.io"}},
+		}
+		for _, test := range tests {
+			auth, err := Credentials(test.image)
+			So(err, ShouldBeNil)
+			So(auth, ShouldResemble, test.auth)
+		}
+
+		_, err := Credentials("registry.example.com/img")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "keychain locked")
+	})
+}
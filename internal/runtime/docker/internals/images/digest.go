@@ -0,0 +1,48 @@
+package images
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// Digest will return the digest the local image was pulled with, as
+// recorded by the registry, or an empty string when the image is not
+// present or was never pulled from a registry.
+func Digest(ctx context.Context, cli *client.Client, image string) (string, error) {
+	inspect, err := cli.ImageInspect(ctx, image)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	repository := Repository(image)
+	for _, repoDigest := range inspect.RepoDigests {
+		name, digest, ok := strings.Cut(repoDigest, "@")
+		if ok && Repository(name) == repository {
+			return digest, nil
+		}
+	}
+	return "", nil
+}
+
+// RemoteDigest will return the digest the registry currently has for an
+// image, without pulling it.
+func RemoteDigest(ctx context.Context, cli *client.Client, image string) (string, error) {
+	auth, err := RegistryAuth(image)
+	if err != nil {
+		return "", err
+	}
+	inspect, err := cli.DistributionInspect(ctx, image, auth)
+	if err != nil {
+		return "", classify(image, err)
+	}
+	return inspect.Descriptor.Digest.String(), nil
+}
+
+// Tag will point the target reference at the source image.
+func Tag(ctx context.Context, cli *client.Client, source string, target string) error {
+	return cli.ImageTag(ctx, source, target)
+}
@@ -3,9 +3,11 @@ package images
 import (
 	"context"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/docker/docker/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
@@ -35,7 +37,7 @@ func TestPullAndList(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Ensure the output from this test contains some expected text.
-	assert.Contains(t, pullResponse, "docker.io/nginx:latest")
+	assert.Contains(t, pullResponse, "docker.io/library/nginx:latest")
 
 	// List the images in the registry.
 	list, err := List(ctx, cli)
@@ -54,3 +56,118 @@ func TestPullAndList(t *testing.T) {
 	_, err = Remove(ctx, cli, id)
 	assert.NoError(t, err)
 }
+
+// TestNormalize will test the normalization of the Docker reference grammar.
+func TestNormalize(t *testing.T) {
+	digest := "sha256:4c2f0e0ad4d6b3ad8a7d0b5e1e7c0a3b0a4e6f2bfbca5d2bcf3b0c1e6a9c0b1d"
+	tests := []struct {
+		image      string
+		normalized string
+		familiar   string
+		repository string
+		registry   string
+		pinned     bool
+	}{
+		{"nginx", "docker.io/library/nginx:latest", "nginx:latest", "nginx", "docker.io", false},
+		{"nginx:1.27", "docker.io/library/nginx:1.27", "nginx:1.27", "nginx", "docker.io", false},
+		{"library/nginx", "docker.io/library/nginx:latest", "nginx:latest", "nginx", "docker.io", false},
+		{"docker.io/library/nginx:latest", "docker.io/library/nginx:latest", "nginx:latest", "nginx", "docker.io", false},
+		{"pygmystack/haproxy", "docker.io/pygmystack/haproxy:latest", "pygmystack/haproxy:latest", "pygmystack/haproxy", "docker.io", false},
+		{"pygmystack/haproxy:latest", "docker.io/pygmystack/haproxy:latest", "pygmystack/haproxy:latest", "pygmystack/haproxy", "docker.io", false},
+		{"index.docker.io/pygmystack/haproxy", "docker.io/pygmystack/haproxy:latest", "pygmystack/haproxy:latest", "pygmystack/haproxy", "docker.io", false},
+		{"quay.io/pygmystack/pygmy", "quay.io/pygmystack/pygmy:latest", "quay.io/pygmystack/pygmy:latest", "quay.io/pygmystack/pygmy", "quay.io", false},
+		{"ghcr.io/org/team/img:v1.2.3", "ghcr.io/org/team/img:v1.2.3", "ghcr.io/org/team/img:v1.2.3", "ghcr.io/org/team/img", "ghcr.io", false},
+		{"localhost/img", "localhost/img:latest", "localhost/img:latest", "localhost/img", "localhost", false},
+		{"localhost:5000/img", "localhost:5000/img:latest", "localhost:5000/img:latest", "localhost:5000/img", "localhost:5000", false},
+		{"localhost:5000/img:dev", "localhost:5000/img:dev", "localhost:5000/img:dev", "localhost:5000/img", "localhost:5000", false},
+		{"registry.example.com:8443/a/b/c:tag_1.0-rc", "registry.example.com:8443/a/b/c:tag_1.0-rc", "registry.example.com:8443/a/b/c:tag_1.0-rc", "registry.example.com:8443/a/b/c", "registry.example.com:8443", false},
+		{"[::1]:5000/img", "[::1]:5000/img:latest", "[::1]:5000/img:latest", "[::1]:5000/img", "[::1]:5000", false},
+		{"pygmystack/haproxy@" + digest, "docker.io/pygmystack/haproxy@" + digest, "pygmystack/haproxy@" + digest, "pygmystack/haproxy", "docker.io", true},
+		{"pygmystack/haproxy:latest@" + digest, "docker.io/pygmystack/haproxy:latest@" + digest, "pygmystack/haproxy:latest@" + digest, "pygmystack/haproxy", "docker.io", true},
+		{"localhost:5000/img@" + digest, "localhost:5000/img@" + digest, "localhost:5000/img@" + digest, "localhost:5000/img", "localhost:5000", true},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			normalized, err := Normalize(test.image)
+			assert.NoError(t, err)
+			assert.Equal(t, test.normalized, normalized)
+			assert.Equal(t, test.familiar, Familiar(test.image))
+			assert.Equal(t, test.repository, Repository(test.image))
+			assert.Equal(t, test.registry, Registry(test.image))
+			assert.Equal(t, test.pinned, Pinned(test.image))
+			assert.True(t, Same(test.image, test.normalized))
+		})
+	}
+}
+
+// TestNormalizeInvalid will test references outside the Docker grammar.
+func TestNormalizeInvalid(t *testing.T) {
+	for _, image := range []string{
+		"",
+		"Nginx",
+		"nginx:",
+		"nginx:tag with spaces",
+		"nginx@sha256:short",
+		"-nginx",
+		"registry:port/img",
+		"img:" + strings.Repeat("a", 129),
+	} {
+		t.Run(image, func(t *testing.T) {
+			_, err := Normalize(image)
+			assert.Error(t, err)
+			assert.False(t, Pinned(image))
+			assert.False(t, Same(image, image))
+		})
+	}
+}
+
+// TestSame will test the comparison of image references.
+func TestSame(t *testing.T) {
+	assert.True(t, Same("nginx", "docker.io/library/nginx:latest"))
+	assert.False(t, Same("nginx", "nginx:1.27"))
+	assert.False(t, Same("pygmystack/haproxy", "quay.io/pygmystack/haproxy"))
+	assert.Equal(t, "pygmystack/haproxy@sha256:abc", WithDigest("pygmystack/haproxy:latest", "sha256:abc"))
+}
+
+// TestMirror will test rewriting images to be pulled from a mirror.
+func TestMirror(t *testing.T) {
+	tests := []struct {
+		image    string
+		mirror   string
+		expected string
+	}{
+		{"pygmystack/haproxy", "mirror.example.com:5000", "mirror.example.com:5000/pygmystack/haproxy"},
+		{"pygmystack/haproxy:latest", "https://mirror.example.com/", "mirror.example.com/pygmystack/haproxy:latest"},
+		{"docker.io/pygmystack/ssh-agent:v1", "mirror.example.com/dockerhub", "mirror.example.com/dockerhub/pygmystack/ssh-agent:v1"},
+		{"pygmystack/haproxy@sha256:4c2f0e0ad4d6b3ad8a7d0b5e1e7c0a3b0a4e6f2bfbca5d2bcf3b0c1e6a9c0b1d", "localhost:5000", "localhost:5000/pygmystack/haproxy@sha256:4c2f0e0ad4d6b3ad8a7d0b5e1e7c0a3b0a4e6f2bfbca5d2bcf3b0c1e6a9c0b1d"},
+		{"nginx", "localhost:5000", "nginx"},
+		{"alpine/socat", "localhost:5000", "alpine/socat"},
+		{"quay.io/pygmystack/pygmy", "localhost:5000", "quay.io/pygmystack/pygmy"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Mirror(test.image, test.mirror))
+	}
+}
+
+// TestPlatforms will test choosing a platform for an image.
+func TestPlatforms(t *testing.T) {
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+	armv7 := v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+	windows := v1.Platform{OS: "windows", Architecture: "amd64"}
+
+	assert.True(t, Supports([]v1.Platform{amd64, arm64}, v1.Platform{OS: "linux", Architecture: "arm64"}))
+	assert.True(t, Supports([]v1.Platform{amd64}, amd64))
+	assert.False(t, Supports([]v1.Platform{amd64}, arm64))
+	assert.False(t, Supports([]v1.Platform{windows}, amd64))
+	assert.False(t, Supports(nil, amd64))
+
+	emulated, ok := Emulated([]v1.Platform{armv7, amd64})
+	assert.True(t, ok)
+	assert.Equal(t, "amd64", emulated.Architecture)
+	emulated, ok = Emulated([]v1.Platform{windows, armv7})
+	assert.True(t, ok)
+	assert.Equal(t, "arm", emulated.Architecture)
+	_, ok = Emulated([]v1.Platform{windows})
+	assert.False(t, ok)
+}
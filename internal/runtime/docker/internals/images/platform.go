@@ -0,0 +1,76 @@
+package images
+
+import (
+	"context"
+
+	"github.com/containerd/platforms"
+	"github.com/docker/docker/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// DaemonPlatform will return the native platform of the Docker daemon,
+// which may differ from the platform pygmy runs on.
+func DaemonPlatform(ctx context.Context, cli *client.Client) (v1.Platform, error) {
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return v1.Platform{}, err
+	}
+	return platforms.Normalize(v1.Platform{OS: version.Os, Architecture: version.Arch}), nil
+}
+
+// Platforms will return the platforms an image can run on. A local image
+// is only available for the platform it was pulled for, otherwise the
+// manifest in the registry lists every variant.
+func Platforms(ctx context.Context, cli *client.Client, image string) ([]v1.Platform, error) {
+	if inspect, err := cli.ImageInspect(ctx, image); err == nil {
+		return []v1.Platform{platforms.Normalize(v1.Platform{
+			OS:           inspect.Os,
+			Architecture: inspect.Architecture,
+			Variant:      inspect.Variant,
+		})}, nil
+	}
+
+	normalized, err := Normalize(image)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := RegistryAuth(normalized)
+	if err != nil {
+		return nil, err
+	}
+	inspect, err := cli.DistributionInspect(ctx, normalized, auth)
+	if err != nil {
+		return nil, classify(normalized, err)
+	}
+	return inspect.Platforms, nil
+}
+
+// Supports will report if one of the available platforms runs natively
+// on platform.
+func Supports(available []v1.Platform, platform v1.Platform) bool {
+	matcher := platforms.Only(platform)
+	for _, p := range available {
+		if matcher.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Emulated will choose the platform to run an image under emulation when
+// it has no native variant: linux/amd64, which emulators support best, or
+// the first Linux platform.
+func Emulated(available []v1.Platform) (v1.Platform, bool) {
+	amd64 := platforms.Only(v1.Platform{OS: "linux", Architecture: "amd64"})
+	for _, p := range available {
+		if amd64.Match(p) {
+			return platforms.Normalize(p), true
+		}
+	}
+	for _, p := range available {
+		if p.OS == "linux" {
+			return platforms.Normalize(p), true
+		}
+	}
+	return v1.Platform{}, false
+}
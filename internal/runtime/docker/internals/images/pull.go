@@ -0,0 +1,166 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	img "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"golang.org/x/term"
+)
+
+var (
+	// ErrNotFound is returned when the registry doesn't have an image.
+	ErrNotFound = errors.New("image not found")
+	// ErrUnauthorized is returned when the registry refuses the
+	// credentials, or requires some.
+	ErrUnauthorized = errors.New("access denied")
+	// ErrRateLimited is returned when the registry refuses more pulls for
+	// now.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnreachable is returned when the registry can't be reached.
+	ErrUnreachable = errors.New("registry unreachable")
+)
+
+// PullOptions configures a pull.
+type PullOptions struct {
+	// Platform is the platform to pull, such as linux/amd64, instead of
+	// the native platform of the daemon.
+	Platform string
+	// Progress receives the progress: a bar for each layer when it is a
+	// terminal, and a line for each step otherwise. Nil hides it.
+	Progress io.Writer
+}
+
+// Pull will pull a Docker image into the daemon, with the credentials
+// the Docker CLI has for its registry. The progress is shown on stderr
+// when it is a terminal.
+func Pull(ctx context.Context, cli *client.Client, image string) (string, error) {
+	return PullWithOptions(ctx, cli, image, PullOptions{Progress: stderrProgress()})
+}
+
+// PullPlatform will pull a Docker image like Pull, for platform when it
+// is not empty.
+func PullPlatform(ctx context.Context, cli *client.Client, image string, platform string) (string, error) {
+	return PullWithOptions(ctx, cli, image, PullOptions{Platform: platform, Progress: stderrProgress()})
+}
+
+// stderrProgress will return stderr when progress can be drawn on it.
+func stderrProgress() io.Writer {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// PullWithOptions will pull a Docker image into the daemon. Errors wrap
+// one of ErrNotFound, ErrUnauthorized, ErrRateLimited or ErrUnreachable
+// when the cause is known.
+func PullWithOptions(ctx context.Context, cli *client.Client, image string, options PullOptions) (string, error) {
+	// References are normalized the way the Docker CLI does, so short
+	// forms such as pygmystack/pygmy pull docker.io/pygmystack/pygmy:latest.
+	image, err := Normalize(image)
+	if err != nil {
+		return image, err
+	}
+
+	auth, err := RegistryAuth(image)
+	if err != nil {
+		return image, err
+	}
+
+	data, err := cli.ImagePull(ctx, image, img.PullOptions{RegistryAuth: auth, Platform: options.Platform})
+	if err != nil {
+		return image, classify(image, err)
+	}
+	defer func() { _ = data.Close() }()
+
+	out := options.Progress
+	fd, terminal := terminalFd(out)
+	if out == nil {
+		out = io.Discard
+	}
+
+	status := &lastStatus{}
+	if err := jsonmessage.DisplayJSONMessagesStream(io.TeeReader(data, status), out, fd, terminal, nil); err != nil {
+		return image, classify(image, err)
+	}
+
+	switch {
+	case strings.Contains(status.status, "Downloaded newer image"):
+		return fmt.Sprintf("Successfully pulled %v", image), nil
+	case strings.Contains(status.status, "Image is up to date"):
+		return fmt.Sprintf("Image %v is up to date", image), nil
+	}
+	return status.status, nil
+}
+
+// terminalFd will return the file descriptor of out when it is a terminal,
+// which progress bars are drawn on.
+func terminalFd(out io.Writer) (uintptr, bool) {
+	if file, ok := out.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		return file.Fd(), true
+	}
+	return 0, false
+}
+
+// lastStatus keeps the status of the last message of a pull, which
+// reports if a newer image was downloaded.
+type lastStatus struct {
+	buffer []byte
+	status string
+}
+
+// Write will read the complete messages written so far.
+func (s *lastStatus) Write(p []byte) (int, error) {
+	s.buffer = append(s.buffer, p...)
+	for {
+		i := bytes.IndexByte(s.buffer, '\n')
+		if i < 0 {
+			break
+		}
+		var message jsonmessage.JSONMessage
+		if json.Unmarshal(s.buffer[:i], &message) == nil && message.Status != "" {
+			s.status = message.Status
+		}
+		s.buffer = s.buffer[i+1:]
+	}
+	return len(p), nil
+}
+
+// classify will wrap a pull error with the sentinel error for its cause,
+// and explain what can be done about it. The daemon reports some causes
+// only in the message, so it is matched as well as the error type.
+func classify(image string, err error) error {
+	message := strings.ToLower(err.Error())
+	contains := func(substrings ...string) bool {
+		for _, substring := range substrings {
+			if strings.Contains(message, substring) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return err
+	case contains("toomanyrequests", "rate limit"):
+		return fmt.Errorf("%w pulling %s, try again later or log in with `docker login %s`: %v", ErrRateLimited, image, Registry(image), err)
+	case cerrdefs.IsUnauthorized(err) || cerrdefs.IsPermissionDenied(err) || contains("pull access denied", "unauthorized", "authentication required", "denied"):
+		return fmt.Errorf("%w to %s, check the image exists and log in with `docker login %s`: %v", ErrUnauthorized, image, Registry(image), err)
+	case cerrdefs.IsNotFound(err) || contains("manifest unknown", "not found", "does not exist"):
+		return fmt.Errorf("%w: %s does not exist: %v", ErrNotFound, image, err)
+	case cerrdefs.IsUnavailable(err) || contains("no such host", "connection refused", "i/o timeout", "tls handshake timeout", "network is unreachable", "connection reset", "server misbehaving"):
+		return fmt.Errorf("%w: cannot reach %s to pull %s, please try again in a few minutes: %v", ErrUnreachable, Registry(image), image, err)
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestClassify will test the classification of pull errors.
+func TestClassify(t *testing.T) {
+	Convey("Images: Pull error classification tests...", t, func() {
+		tests := []struct {
+			err      error
+			sentinel error
+		}{
+			{errors.New("Error response from daemon: pull access denied for pygmy/missing, repository does not exist or may require 'docker login'"), ErrUnauthorized},
+			{fmt.Errorf("wrapped: %w", cerrdefs.ErrUnauthenticated), ErrUnauthorized},
+			{errors.New("Error response from daemon: manifest for nginx:missing not found: manifest unknown"), ErrNotFound},
+			{fmt.Errorf("wrapped: %w", cerrdefs.ErrNotFound), ErrNotFound},
+			{&jsonmessage.JSONError{Message: "toomanyrequests: You have reached your pull rate limit."}, ErrRateLimited},
+			{errors.New(`Get "https://registry-1.docker.io/v2/": dial tcp: lookup registry-1.docker.io: no such host`), ErrUnreachable},
+			{errors.New(`Get "https://localhost:5000/v2/": dial tcp 127.0.0.1:5000: connect: connection refused`), ErrUnreachable},
+			{fmt.Errorf("wrapped: %w", cerrdefs.ErrUnavailable), ErrUnreachable},
+		}
+		for _, test := range tests {
+			err := classify("docker.io/library/nginx:latest", test.err)
+			So(errors.Is(err, test.sentinel), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, test.err.Error())
+		}
+
+		other := errors.New("something else")
+		So(classify("nginx", other), ShouldEqual, other)
+		So(classify("nginx", context.Canceled), ShouldEqual, context.Canceled)
+	})
+}
+
+// TestLastStatus will test reading the final status of a pull stream
+// written in pieces.
+func TestLastStatus(t *testing.T) {
+	Convey("Images: Pull status tests...", t, func() {
+		s := &lastStatus{}
+		_, _ = s.Write([]byte(`{"status":"Pulling from library/nginx","id":"latest"}` + "\n" + `{"status":"Downloa`))
+		So(s.status, ShouldEqual, "Pulling from library/nginx")
+		_, _ = s.Write([]byte(`ding","progressDetail":{"current":1,"total":2},"id":"abc"}` + "\n" + `{"progressDetail":{}}` + "\n"))
+		So(s.status, ShouldEqual, "Downloading")
+		_, _ = s.Write([]byte(`{"status":"Status: Downloaded newer image for nginx:latest"}` + "\n"))
+		So(s.status, ShouldEqual, "Status: Downloaded newer image for nginx:latest")
+	})
+}
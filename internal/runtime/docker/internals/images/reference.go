@@ -0,0 +1,102 @@
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// Normalize will return the canonical form of an image reference, with
+// its registry, its full repository path and its tag or digest, such as
+// docker.io/library/nginx:latest for nginx. A reference without a tag or
+// digest gets the latest tag. The whole Docker reference grammar is
+// supported: registries with ports, nested repositories and digests.
+func Normalize(image string) (string, error) {
+	named, err := parse(image)
+	if err != nil {
+		return image, err
+	}
+	return reference.TagNameOnly(named).String(), nil
+}
+
+// Familiar will return the short form of an image reference, as the
+// Docker CLI displays it, such as nginx:latest.
+func Familiar(image string) string {
+	named, err := parse(image)
+	if err != nil {
+		return image
+	}
+	return reference.FamiliarString(reference.TagNameOnly(named))
+}
+
+// Same will report if two image references refer to the same image, such
+// as nginx and docker.io/library/nginx:latest.
+func Same(a string, b string) bool {
+	na, errA := Normalize(a)
+	nb, errB := Normalize(b)
+	return errA == nil && errB == nil && na == nb
+}
+
+// Pinned will report if an image reference is pinned to a digest, such as
+// pygmystack/haproxy@sha256:..., in which case it never changes when
+// pulled.
+func Pinned(image string) bool {
+	named, err := parse(image)
+	if err != nil {
+		return false
+	}
+	_, ok := named.(reference.Canonical)
+	return ok
+}
+
+// Repository will return the repository of an image reference, without
+// its tag or digest, such as pygmystack/haproxy.
+func Repository(image string) string {
+	named, err := parse(image)
+	if err != nil {
+		return image
+	}
+	return reference.FamiliarName(named)
+}
+
+// Registry will return the registry an image reference is pulled from,
+// such as docker.io.
+func Registry(image string) string {
+	named, err := parse(image)
+	if err != nil {
+		return ""
+	}
+	return reference.Domain(named)
+}
+
+// WithDigest will return the reference of an image in the same repository
+// with the given digest.
+func WithDigest(image string, digest string) string {
+	return fmt.Sprintf("%s@%s", Repository(image), digest)
+}
+
+// Mirror will rewrite a pygmystack image on Docker Hub to be pulled from
+// a registry mirror, such as mirror.example.com:5000 or
+// mirror.example.com/dockerhub, keeping its path, tag and digest. Other
+// images, or all images when mirror is empty, are returned as they are.
+func Mirror(image string, mirror string) string {
+	named, err := parse(image)
+	if mirror == "" || err != nil || reference.Domain(named) != "docker.io" || !strings.HasPrefix(reference.Path(named), "pygmystack/") {
+		return image
+	}
+	mirror = strings.TrimPrefix(mirror, "https://")
+	mirror = strings.TrimPrefix(mirror, "http://")
+	mirror = strings.TrimSuffix(mirror, "/")
+	return fmt.Sprintf("%s/%s", mirror, reference.FamiliarString(named))
+}
+
+// parse will parse an image reference, accepting the short forms the
+// Docker CLI accepts.
+func parse(image string) (reference.Named, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	return named, nil
+}
@@ -62,6 +62,11 @@ func Status(ctx context.Context, cli *client.Client, network string) (bool, erro
 	return false, nil
 }
 
+// List will return all Docker networks.
+func List(ctx context.Context, cli *client.Client) ([]networktypes.Summary, error) {
+	return cli.NetworkList(ctx, networktypes.ListOptions{})
+}
+
 // Get will use the Docker API to retrieve a Docker network
 // which has a given name.
 func Get(ctx context.Context, cli *client.Client, name string) (networktypes.Inspect, error) {
@@ -103,3 +108,22 @@ func Connected(ctx context.Context, cli *client.Client, network string, containe
 	}
 	return false, fmt.Errorf("network was found without the container connected")
 }
+
+// Disconnect will disconnect a container from a network.
+func Disconnect(ctx context.Context, cli *client.Client, network string, containerName string, force bool) error {
+	return cli.NetworkDisconnect(ctx, network, containerName, force)
+}
+
+// Endpoints will return the names of the containers attached to a network
+// indexed by their container ID.
+func Endpoints(ctx context.Context, cli *client.Client, network string) (map[string]string, error) {
+	n, err := cli.NetworkInspect(ctx, network, networktypes.InspectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make(map[string]string, len(n.Containers))
+	for id, endpoint := range n.Containers {
+		endpoints[id] = endpoint.Name
+	}
+	return endpoints, nil
+}
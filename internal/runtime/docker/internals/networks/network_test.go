@@ -240,3 +240,65 @@ func TestConnected(t *testing.T) {
 	err = Remove(ctx, cli, id)
 	assert.NoError(t, err)
 }
+
+// TestEndpoints will test the listing and disconnection of network endpoints.
+func TestEndpoints(t *testing.T) {
+	ctx, cli := testSetup()
+	config, hostconfig, networkconfig := sampleData()
+	id := fmt.Sprintf("testNetwork-%s", randomString(10))
+	containerName := fmt.Sprintf("testContainer-%s", randomString(10))
+
+	config.Labels = map[string]string{
+		"pygmy.name": containerName,
+	}
+
+	// Pull the image
+	_, err := images.Pull(ctx, cli, config.Image)
+	assert.NoError(t, err)
+
+	// Create a container to connect.
+	_, err = containers.Create(ctx, cli, containerName, config, hostconfig, networkconfig)
+	assert.NoError(t, err)
+
+	// Start the container to connect.
+	err = containers.Start(ctx, cli, containerName, container.StartOptions{})
+	assert.NoError(t, err)
+
+	network := &network.Inspect{
+		Name: id,
+		Labels: map[string]string{
+			"pygmy.name": id,
+		},
+	}
+
+	err = Create(ctx, cli, network)
+	assert.NoError(t, err)
+
+	err = Connect(ctx, cli, id, containerName)
+	assert.NoError(t, err)
+
+	endpoints, err := Endpoints(ctx, cli, id)
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 1)
+	for _, name := range endpoints {
+		assert.Equal(t, containerName, name)
+	}
+
+	err = Disconnect(ctx, cli, id, containerName, false)
+	assert.NoError(t, err)
+
+	endpoints, err = Endpoints(ctx, cli, id)
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 0)
+
+	// Stop the container.
+	err = containers.Stop(ctx, cli, containerName)
+	assert.NoError(t, err)
+
+	// Remove the container.
+	err = containers.Remove(ctx, cli, containerName)
+	assert.NoError(t, err)
+
+	err = Remove(ctx, cli, id)
+	assert.NoError(t, err)
+}
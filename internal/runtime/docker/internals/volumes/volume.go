@@ -2,8 +2,20 @@ package volumes
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
 )
 
 // Exists will check if a Docker volume has been created.
@@ -16,6 +28,15 @@ func Exists(ctx context.Context, cli *client.Client, volume string) (bool, error
 	return true, nil
 }
 
+// List will return all Docker volumes.
+func List(ctx context.Context, cli *client.Client) ([]*volume.Volume, error) {
+	volumes, err := cli.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return volumes.Volumes, nil
+}
+
 // Get will return the full contents of a types.Volume from the API.
 func Get(ctx context.Context, cli *client.Client, name string) (volume.Volume, error) {
 	volumes, err := cli.VolumeList(ctx, volume.ListOptions{})
@@ -46,7 +67,118 @@ func Create(ctx context.Context, cli *client.Client, volumeInput volume.Volume)
 	})
 }
 
-// Remove will remove a Docker volume, which will be used exclusively for testing.
+// Remove will remove a Docker volume.
 func Remove(ctx context.Context, cli *client.Client, volume string) error {
 	return cli.VolumeRemove(ctx, volume, false)
 }
+
+// Inspect will return a Docker volume, or an error if it does not exist.
+func Inspect(ctx context.Context, cli *client.Client, name string) (volume.Volume, error) {
+	return cli.VolumeInspect(ctx, name)
+}
+
+// UsedBy will return the names of the containers which mount a volume.
+func UsedBy(ctx context.Context, cli *client.Client, name string) ([]string, error) {
+	list, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("volume", name)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, c := range list {
+		names = append(names, strings.TrimPrefix(c.Names[0], "/"))
+	}
+	return names, nil
+}
+
+// helperMountPoint is where the volume is mounted in the helper container.
+const helperMountPoint = "/volume"
+
+// withHelper will create (but not start) a throwaway container using image
+// with the volume mounted, run fn with the container ID and remove the
+// container afterwards. Archives can be copied to and from a created
+// container without running anything inside it. The image is only pulled
+// when it is missing, so archives can be copied offline.
+func withHelper(ctx context.Context, cli *client.Client, name string, image string, labels map[string]string, fn func(id string) error) error {
+	if image == "" {
+		return fmt.Errorf("no helper image was provided")
+	}
+	if !images.Present(ctx, cli, image) {
+		if _, err := images.Pull(ctx, cli, image); err != nil {
+			return err
+		}
+	}
+
+	// Each helper has a unique name, so a helper left behind by an
+	// interrupted run or used by a concurrent copy doesn't conflict.
+	resp, err := containers.Create(ctx, cli, fmt.Sprintf("pygmy-volume-helper-%s-%s", name, suffix()), container.Config{
+		Image:  image,
+		Labels: labels,
+	}, container.HostConfig{
+		Binds: []string{fmt.Sprintf("%s:%s", name, helperMountPoint)},
+	}, network.NetworkingConfig{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = containers.Remove(ctx, cli, resp.ID) }()
+
+	return fn(resp.ID)
+}
+
+// suffix will return a short random suffix for the names of throwaway
+// resources.
+func suffix() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Backup will write a tar archive of the content of a volume to w. The
+// archive contains a single top-level directory named "volume".
+func Backup(ctx context.Context, cli *client.Client, name string, image string, labels map[string]string, w io.Writer) error {
+	if s, _ := Exists(ctx, cli, name); !s {
+		return fmt.Errorf("volume %s does not exist", name)
+	}
+	return withHelper(ctx, cli, name, image, labels, func(id string) error {
+		archive, _, err := cli.CopyFromContainer(ctx, id, helperMountPoint)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = archive.Close() }()
+		_, err = io.Copy(w, archive)
+		return err
+	})
+}
+
+// Copy will copy the content of the volume from into the volume to.
+func Copy(ctx context.Context, cli *client.Client, from string, to string, image string, labels map[string]string) error {
+	r, w := io.Pipe()
+	go func() {
+		_ = w.CloseWithError(Backup(ctx, cli, from, image, labels, w))
+	}()
+	err := Restore(ctx, cli, to, image, labels, r)
+	_ = r.CloseWithError(err)
+	return err
+}
+
+// Staging will create an empty volume to restore into before the volume
+// name is replaced. It uses the default driver, as the options of another
+// driver may refer to the storage of the volume being replaced.
+func Staging(ctx context.Context, cli *client.Client, name string, labels map[string]string) (volume.Volume, error) {
+	return Create(ctx, cli, volume.Volume{
+		Name:   fmt.Sprintf("%s-restore-%s", name, suffix()),
+		Labels: labels,
+	})
+}
+
+// Restore will extract a tar archive created by Backup into a volume.
+func Restore(ctx context.Context, cli *client.Client, name string, image string, labels map[string]string, r io.Reader) error {
+	if s, _ := Exists(ctx, cli, name); !s {
+		return fmt.Errorf("volume %s does not exist", name)
+	}
+	return withHelper(ctx, cli, name, image, labels, func(id string) error {
+		return cli.CopyToContainer(ctx, id, "/", r, container.CopyToContainerOptions{})
+	})
+}
@@ -1,10 +1,13 @@
 package volumes
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"fmt"
 	volume2 "github.com/docker/docker/api/types/volume"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"math/rand"
 	"testing"
 	"time"
@@ -113,3 +116,114 @@ func TestExists(t *testing.T) {
 	err = Remove(ctx, cli, volumeName)
 	assert.NoError(t, err)
 }
+
+// TestBackupAndRestore will test restoring an archive into a volume and
+// backing the volume up again.
+func TestBackupAndRestore(t *testing.T) {
+	ctx, cli := testSetup()
+	volumeName := fmt.Sprintf("testVolume-%s", randomString(10))
+	volume := volume2.Volume{
+		Name: volumeName,
+	}
+
+	// Create a volume.
+	_, err := Create(ctx, cli, volume)
+	assert.NoError(t, err)
+
+	// Build an archive in the format Backup produces.
+	content := []byte("hello from pygmy\n")
+	input := new(bytes.Buffer)
+	tw := tar.NewWriter(input)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "volume/", Typeflag: tar.TypeDir, Mode: 0755}))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "volume/hello.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+	_, err = tw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+
+	// Restore the archive into the volume.
+	err = Restore(ctx, cli, volumeName, "nginx", nil, input)
+	assert.NoError(t, err)
+
+	// Back the volume up.
+	output := new(bytes.Buffer)
+	err = Backup(ctx, cli, volumeName, "nginx", nil, output)
+	assert.NoError(t, err)
+
+	// Check the file has survived the round trip.
+	found := false
+	tr := tar.NewReader(output)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if header.Name == "volume/hello.txt" {
+			data, _ := io.ReadAll(tr)
+			assert.Equal(t, content, data)
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	// Check the helper container has been removed.
+	users, err := UsedBy(ctx, cli, volumeName)
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+
+	// Remove the volume.
+	err = Remove(ctx, cli, volumeName)
+	assert.NoError(t, err)
+}
+
+// TestStagingAndCopy will test restoring into a staging volume and
+// copying it over another volume.
+func TestStagingAndCopy(t *testing.T) {
+	ctx, cli := testSetup()
+	volumeName := fmt.Sprintf("testVolume-%s", randomString(10))
+	_, err := Create(ctx, cli, volume2.Volume{Name: volumeName})
+	assert.NoError(t, err)
+
+	// Create a staging volume with the given labels.
+	staging, err := Staging(ctx, cli, volumeName, map[string]string{"pygmy.test": "true"})
+	assert.NoError(t, err)
+	assert.Contains(t, staging.Name, volumeName+"-restore-")
+	assert.Equal(t, "true", staging.Labels["pygmy.test"])
+
+	// A corrupt archive is rejected.
+	err = Restore(ctx, cli, staging.Name, "nginx", nil, bytes.NewReader([]byte("not an archive")))
+	assert.Error(t, err)
+
+	// Restore an archive into the staging volume.
+	content := []byte("hello from pygmy\n")
+	input := new(bytes.Buffer)
+	tw := tar.NewWriter(input)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "volume/", Typeflag: tar.TypeDir, Mode: 0755}))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "volume/hello.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+	_, err = tw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, Restore(ctx, cli, staging.Name, "nginx", nil, input))
+
+	// Copy the staging volume over the volume.
+	assert.NoError(t, Copy(ctx, cli, staging.Name, volumeName, "nginx", nil))
+	output := new(bytes.Buffer)
+	assert.NoError(t, Backup(ctx, cli, volumeName, "nginx", nil, output))
+	found := false
+	tr := tar.NewReader(output)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if header.Name == "volume/hello.txt" {
+			data, _ := io.ReadAll(tr)
+			assert.Equal(t, content, data)
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	// Remove the volumes.
+	assert.NoError(t, Remove(ctx, cli, staging.Name))
+	assert.NoError(t, Remove(ctx, cli, volumeName))
+}
@@ -5,6 +5,10 @@ import (
 	networktypes "github.com/docker/docker/api/types/network"
 )
 
+// InstanceLabel is the label pygmy uses to mark the containers, networks
+// and volumes it creates with the identifier of the installation.
+const InstanceLabel = "pygmy.instance"
+
 type Service struct {
 	Config        containertypes.Config
 	HostConfig    containertypes.HostConfig
@@ -20,4 +24,9 @@ type Params struct {
 	Domain string
 	// TLSCertPath is the TLS Certificate Path.
 	TLSCertPath string
+	// IPv6 enables dual-stack behaviour, such as AAAA records.
+	IPv6 bool
+	// Address is the address the domain resolves to, which is 127.0.0.1
+	// when it is empty.
+	Address string
 }
@@ -12,13 +12,18 @@ import (
 
 // New will provide the standard object for the dnsmasq container.
 func New(c *docker.Params) docker.Service {
-	return docker.Service{
+	address := c.Address
+	if address == "" {
+		address = "127.0.0.1"
+	}
+
+	service := docker.Service{
 		Config: container.Config{
 			Image: "pygmystack/dnsmasq",
 			Cmd: []string{
 				"--log-facility=-",
 				"-A",
-				fmt.Sprintf("/%s/127.0.0.1", c.Domain),
+				fmt.Sprintf("/%s/%s", c.Domain, address),
 			},
 			Labels: map[string]string{
 				"pygmy.defaults": "true",
@@ -52,4 +57,11 @@ func New(c *docker.Params) docker.Service {
 		},
 		NetworkConfig: network.NetworkingConfig{},
 	}
+
+	// Answer AAAA queries for the domain as well as A queries.
+	if c.IPv6 {
+		service.Config.Cmd = append(service.Config.Cmd, "-A", fmt.Sprintf("/%s/::1", c.Domain))
+	}
+
+	return service
 }
@@ -33,4 +33,10 @@ func Test(t *testing.T) {
 		So(obj.HostConfig.RestartPolicy.Name, ShouldEqual, container.RestartPolicyMode("unless-stopped"))
 		So(obj.HostConfig.RestartPolicy.MaximumRetryCount, ShouldBeZeroValue)
 	})
+
+	Convey("DNSMasq: IPv6 tests...", t, func() {
+		obj := dnsmasq.New(&docker.Params{Domain: "docker.amazee.io", IPv6: true})
+
+		So(fmt.Sprint(obj.Config.Cmd), ShouldEqual, fmt.Sprint([]string{"--log-facility=-", "-A", "/docker.amazee.io/127.0.0.1", "-A", "/docker.amazee.io/::1"}))
+	})
 }
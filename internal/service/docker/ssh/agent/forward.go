@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/pygmystack/pygmy/internal/runtime/docker"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
+	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/images"
+)
+
+// SocketDir is the directory project containers mount from the SSH agent
+// container, with `volumes_from`.
+const SocketDir = "/tmp/amazeeio_ssh-agent"
+
+// Socket is the path of the agent socket in project containers.
+const Socket = SocketDir + "/socket"
+
+// hostSocket is where the host agent socket is mounted in the forwarder.
+const hostSocket = "/run/host-ssh-agent.sock"
+
+// DefaultForwarderImage is the image used to proxy the host agent socket.
+const DefaultForwarderImage = "alpine/socat"
+
+// HostSocket will return the path of the host agent socket which can be
+// mounted into a container. Docker Desktop for Mac can't mount the macOS
+// socket, and provides the host agent at a fixed path in its VM instead.
+func HostSocket() (string, error) {
+	if runtime.GOOS == "darwin" {
+		return "/run/host-services/ssh-auth.sock", nil
+	}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		return socket, nil
+	}
+	return "", fmt.Errorf("SSH_AUTH_SOCK is not set, is an SSH agent running on the host?")
+}
+
+// NewForwarder will provide an SSH agent container which proxies the host
+// agent socket, instead of running an agent. It replaces the container
+// from New, so project containers find the socket in the same place. The
+// proxy socket is world-writable, as project containers may not run as
+// the user who owns the host socket.
+func NewForwarder(socket string, image string) docker.Service {
+	if image == "" {
+		image = DefaultForwarderImage
+	}
+	service := New()
+	service.Config.Image = image
+	service.Config.Labels["pygmy.agent.mode"] = "forward"
+	service.Config.Entrypoint = []string{"socat"}
+	service.Config.Cmd = []string{
+		fmt.Sprintf("UNIX-LISTEN:%s,fork,unlink-early,mode=0777", Socket),
+		fmt.Sprintf("UNIX-CONNECT:%s", hostSocket),
+	}
+	// The socket directory must be a volume to be shared with volumes_from.
+	service.Config.Volumes = map[string]struct{}{SocketDir: {}}
+	if socket != "" {
+		service.HostConfig.Binds = []string{fmt.Sprintf("%s:%s", socket, hostSocket)}
+	}
+	return service
+}
+
+// Reachable will list the identities which project containers can reach
+// through the socket shared by the SSH agent container name, by running
+// `ssh-add -l` in a throwaway container using image. The image is only
+// pulled when it isn't present, and each probe has its own name so
+// probes can run alongside each other.
+func Reachable(ctx context.Context, cli *client.Client, name string, image string) ([]Identity, error) {
+	if !images.Present(ctx, cli, image) {
+		if _, err := images.Pull(ctx, cli, image); err != nil {
+			return nil, err
+		}
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	resp, err := containers.Create(ctx, cli, fmt.Sprintf("%s-probe-%s", name, hex.EncodeToString(suffix)), container.Config{
+		Image:      image,
+		Entrypoint: []string{"ssh-add"},
+		Cmd:        []string{"-l"},
+		Env:        []string{fmt.Sprintf("SSH_AUTH_SOCK=%s", Socket)},
+		Labels:     map[string]string{"pygmy.purpose": "agentprobe"},
+	}, container.HostConfig{
+		VolumesFrom: []string{name},
+	}, network.NetworkingConfig{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = containers.Remove(ctx, cli, resp.ID) }()
+
+	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return nil, err
+	}
+
+	var exitCode int64
+	select {
+	case err := <-errCh:
+		return nil, err
+	case status := <-statusCh:
+		exitCode = status.StatusCode
+	}
+
+	logs, err := containers.Logs(ctx, cli, resp.ID)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, bytes.NewReader(logs)); err != nil {
+		return nil, err
+	}
+
+	// ssh-add exits with 1 when the agent has no identities, and with 2
+	// when it can't connect to the agent.
+	if exitCode > 1 {
+		return nil, fmt.Errorf("the host SSH agent is not reachable: %s", strings.TrimSpace(stderr.String()))
+	}
+	return ParseIdentities(stdout.String()), nil
+}
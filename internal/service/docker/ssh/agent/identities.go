@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Identity is a key loaded in the SSH agent.
+type Identity struct {
+	Bits        int
+	Fingerprint string
+	Type        string
+	Comment     string
+}
+
+// identityLine matches a line of `ssh-add -l` output, for example
+// "256 SHA256:abc... user@example.com (ED25519)".
+var identityLine = regexp.MustCompile(`^(\d+) (\S+) (.*?) ?\(([^()]+)\)$`)
+
+// ParseIdentities will parse the output of `ssh-add -l`. Lines which do
+// not describe an identity, such as "The agent has no identities.", are
+// ignored.
+func ParseIdentities(output string) []Identity {
+	identities := []Identity{}
+	for _, line := range strings.Split(output, "\n") {
+		match := identityLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		bits, _ := strconv.Atoi(match[1])
+		identities = append(identities, Identity{
+			Bits:        bits,
+			Fingerprint: match[2],
+			Comment:     match[3],
+			Type:        match[4],
+		})
+	}
+	return identities
+}
+
+// PublicKey is a key loaded in the SSH agent, as listed by `ssh-add -L`.
+type PublicKey struct {
+	// Key is the public key in the authorized_keys format.
+	Key         string
+	Fingerprint string
+	// KeyFingerprint is the fingerprint of the key a certificate was
+	// issued for, and is the same as Fingerprint for other keys.
+	KeyFingerprint string
+	Comment        string
+}
+
+// Matches will report if the key, or the key of a certificate, has the
+// given SHA256 fingerprint.
+func (k PublicKey) Matches(fingerprint string) bool {
+	return k.Fingerprint == fingerprint || k.KeyFingerprint == fingerprint
+}
+
+// ParsePublicKeys will parse the output of `ssh-add -L`. Lines which do
+// not contain a public key are ignored.
+func ParsePublicKeys(output string) []PublicKey {
+	keys := []PublicKey{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			continue
+		}
+		certified := key
+		if cert, ok := key.(*ssh.Certificate); ok {
+			certified = cert.Key
+		}
+		keys = append(keys, PublicKey{
+			Key:            line,
+			Fingerprint:    ssh.FingerprintSHA256(key),
+			KeyFingerprint: ssh.FingerprintSHA256(certified),
+			Comment:        comment,
+		})
+	}
+	return keys
+}
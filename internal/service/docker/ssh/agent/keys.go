@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key is an SSH key on the host, as it will be added to the agent.
+type Key struct {
+	// Path is the path of the private key.
+	Path string
+	// PublicKey is the public key, read from the .pub file or the private
+	// key.
+	PublicKey ssh.PublicKey
+	// Certificate is the certificate in the -cert.pub file, if any, which
+	// ssh-add will add alongside the key.
+	Certificate *ssh.Certificate
+	// Comment is the comment of the public key, if any.
+	Comment string
+	// Encrypted reports if the private key is protected by a passphrase.
+	Encrypted bool
+}
+
+// Fingerprint will return the SHA256 fingerprint of the key, as listed by
+// `ssh-add -l`. A certificate has the fingerprint of its key.
+func (k Key) Fingerprint() string {
+	return ssh.FingerprintSHA256(k.PublicKey)
+}
+
+// Type will return the type of the key, such as "ssh-ed25519".
+func (k Key) Type() string {
+	return k.PublicKey.Type()
+}
+
+// SecurityKey will report if the key is held by a FIDO security key, in
+// which case the private key file only references the hardware.
+func (k Key) SecurityKey() bool {
+	return strings.HasPrefix(k.Type(), "sk-")
+}
+
+// PrivateKeyPath will return the path of the private key for path, which
+// may be the private key, its public key or its certificate.
+func PrivateKeyPath(path string) string {
+	if strings.HasSuffix(path, "-cert.pub") {
+		return strings.TrimSuffix(path, "-cert.pub")
+	}
+	return strings.TrimSuffix(path, ".pub")
+}
+
+// LoadKey will read the SSH key at path, which may be the private key,
+// its public key or its certificate. The private key must exist, but
+// doesn't need to be decrypted: the public key is read from the .pub file
+// or the private key itself.
+func LoadKey(path string) (Key, error) {
+	key := Key{Path: PrivateKeyPath(path)}
+
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		return key, err
+	}
+	private, encrypted, err := parsePrivateKey(data)
+	if err != nil {
+		return key, fmt.Errorf("%s is not a valid SSH private key: %w", key.Path, err)
+	}
+	key.Encrypted = encrypted
+	key.PublicKey = private
+
+	if data, err := os.ReadFile(key.Path + ".pub"); err == nil {
+		public, comment, err := parsePublicKey(data)
+		if err != nil {
+			return key, fmt.Errorf("%s.pub is not a valid SSH public key: %w", key.Path, err)
+		}
+		if private != nil && ssh.FingerprintSHA256(private) != ssh.FingerprintSHA256(public) {
+			return key, fmt.Errorf("%s.pub does not match the private key %s", key.Path, key.Path)
+		}
+		key.PublicKey = public
+		key.Comment = comment
+	}
+
+	if data, err := os.ReadFile(key.Path + "-cert.pub"); err == nil {
+		public, _, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return key, fmt.Errorf("%s-cert.pub is not a valid SSH certificate: %w", key.Path, err)
+		}
+		cert, ok := public.(*ssh.Certificate)
+		if !ok {
+			return key, fmt.Errorf("%s-cert.pub is not an SSH certificate", key.Path)
+		}
+		key.Certificate = cert
+		if key.PublicKey == nil {
+			key.PublicKey = cert.Key
+		}
+	}
+
+	if key.PublicKey == nil {
+		return key, fmt.Errorf("could not read the public key of %s, %s.pub is missing", key.Path, key.Path)
+	}
+	return key, nil
+}
+
+// Fingerprint will return the SHA256 fingerprint of the key at path.
+func Fingerprint(path string) (string, error) {
+	key, err := LoadKey(path)
+	if err != nil {
+		return "", err
+	}
+	return key.Fingerprint(), nil
+}
+
+// parsePublicKey will parse a public key in the authorized_keys format. A
+// certificate is reduced to its key.
+func parsePublicKey(data []byte) (ssh.PublicKey, string, error) {
+	public, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if cert, ok := public.(*ssh.Certificate); ok {
+		return cert.Key, comment, nil
+	}
+	return public, comment, nil
+}
+
+// openSSHMagic starts the content of an OpenSSH private key.
+const openSSHMagic = "openssh-key-v1\x00"
+
+// parsePrivateKey will check a private key and return its public key when
+// it can be read without the passphrase. Keys in the OpenSSH format always
+// include the public key, which also covers types such as FIDO security
+// keys which can't be parsed otherwise.
+func parsePrivateKey(data []byte) (ssh.PublicKey, bool, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, false, errors.New("no key found")
+	}
+
+	if block.Type == "OPENSSH PRIVATE KEY" {
+		if !strings.HasPrefix(string(block.Bytes), openSSHMagic) {
+			return nil, false, errors.New("invalid OpenSSH private key")
+		}
+		var envelope struct {
+			CipherName   string
+			KdfName      string
+			KdfOpts      string
+			NumKeys      uint32
+			PubKey       []byte
+			PrivKeyBlock []byte
+		}
+		if err := ssh.Unmarshal(block.Bytes[len(openSSHMagic):], &envelope); err != nil {
+			return nil, false, err
+		}
+		public, err := ssh.ParsePublicKey(envelope.PubKey)
+		if err != nil {
+			return nil, false, err
+		}
+		return public, envelope.CipherName != "none", nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return missing.PublicKey, true, nil
+		}
+		return nil, false, err
+	}
+	return signer.PublicKey(), false, nil
+}
+
+// HasFingerprint will report if an identity with fingerprint is loaded.
+func HasFingerprint(identities []Identity, fingerprint string) bool {
+	for _, identity := range identities {
+		if identity.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrIncorrectPassphrase is returned when a key can't be decrypted.
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+// Askpass will ask for the passphrase of a key with the program in
+// SSH_ASKPASS, following the rules of ssh-add: SSH_ASKPASS_REQUIRE=never
+// disables it, "prefer" and "force" use it even with a terminal, and
+// otherwise it is only used without a terminal. It reports false when no
+// program should be used.
+func Askpass(prompt string, terminal bool) ([]byte, bool, error) {
+	program := os.Getenv("SSH_ASKPASS")
+	switch os.Getenv("SSH_ASKPASS_REQUIRE") {
+	case "never":
+		return nil, false, nil
+	case "prefer", "force":
+	default:
+		if terminal {
+			return nil, false, nil
+		}
+	}
+	if program == "" {
+		return nil, false, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(program, prompt)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, true, fmt.Errorf("%s failed: %v %s", program, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return bytes.TrimRight(output, "\r\n"), true, nil
+}
+
+// stdinScript reads the passphrase from the first line of its input and
+// runs ssh-add on the key in the rest of it. ssh-add asks an askpass
+// helper for the passphrase, which the helper finds in the environment
+// of ssh-add, so neither the passphrase nor the key are written to a
+// file.
+const stdinScript = `IFS= read -r PYGMY_PASSPHRASE
+export PYGMY_PASSPHRASE
+cat > /tmp/pygmy-askpass <<'ASKPASS'
+#!/bin/sh
+printf '%s\n' "$PYGMY_PASSPHRASE"
+ASKPASS
+chmod 700 /tmp/pygmy-askpass
+SSH_ASKPASS=/tmp/pygmy-askpass SSH_ASKPASS_REQUIRE=force exec ssh-add "$@" -`
+
+// StdinCommand will return the command which adds the key given by
+// StdinInput to the agent, with args given to ssh-add.
+func StdinCommand(args ...string) []string {
+	return append([]string{"sh", "-c", stdinScript, "ssh-add"}, args...)
+}
+
+// StdinInput will return the input of StdinCommand: the passphrase on the
+// first line, followed by the private key. An encrypted key is sent as it
+// is, once the passphrase has been checked. The comment replaces the
+// comment of an unencrypted key, which is otherwise named after its path
+// when it has no comment, as ssh-add does. The caller should clear the
+// input once it has been used.
+func StdinInput(k Key, passphrase []byte, comment string) ([]byte, error) {
+	if bytes.ContainsAny(passphrase, "\r\n") {
+		return nil, fmt.Errorf("passphrases containing line breaks are not supported")
+	}
+	data, err := os.ReadFile(k.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.Encrypted {
+		_, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrIncorrectPassphrase
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err := ssh.ParseRawPrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		if comment == "" {
+			comment = k.Comment
+		}
+		if comment == "" {
+			comment = k.Path
+		}
+		block, err := ssh.MarshalPrivateKey(raw, comment)
+		if err != nil {
+			return nil, err
+		}
+		clear(data)
+		data = pem.EncodeToMemory(block)
+	}
+
+	input := make([]byte, 0, len(passphrase)+1+len(data))
+	input = append(append(append(input, passphrase...), '\n'), data...)
+	clear(data)
+	return input, nil
+}
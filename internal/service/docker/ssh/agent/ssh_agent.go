@@ -3,14 +3,12 @@ package agent
 import (
 	"context"
 	"errors"
-	"fmt"
 	"os"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
-	"golang.org/x/crypto/ssh"
 
 	"github.com/pygmystack/pygmy/internal/runtime/docker"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals/containers"
@@ -58,50 +56,26 @@ func List(ctx context.Context, cli *client.Client, service *docker.Service) ([]b
 	return containers.Logs(ctx, cli, name)
 }
 
-// Validate will validate if an SSH key is valid.
-func Validate(filePath string) (bool, error) {
-
-	filePath = strings.TrimRight(filePath, ".pub")
-	content, err := os.ReadFile(filePath)
+// Search will determine if an SSH key has been added to the agent running
+// in the service container, by comparing fingerprints. A key which doesn't
+// exist on the host can't have been added.
+func Search(ctx context.Context, cli *client.Client, service *docker.Service, path string) (bool, error) {
+	if _, err := os.Stat(PrivateKeyPath(path)); os.IsNotExist(err) {
+		return false, nil
+	}
+	key, err := LoadKey(path)
 	if err != nil {
-		fmt.Println("Err")
+		return false, err
 	}
 
-	_, err = ssh.ParsePrivateKey(content)
+	name, _ := service.GetFieldString(ctx, cli, "name")
+	result, err := containers.ExecCommand(ctx, cli, name, []string{"ssh-add", "-l"})
 	if err != nil {
 		return false, err
 	}
-
-	return true, nil
-}
-
-// Search will determine if an SSH key has been added to the agent.
-func Search(ctx context.Context, cli *client.Client, service *docker.Service, key string) (bool, error) {
-	result := false
-	if _, err := os.Stat(key); !os.IsNotExist(err) {
-		stripped := strings.Trim(key, ".pub")
-		data, err := os.ReadFile(stripped + ".pub")
-		if err != nil {
-			return false, err
-		}
-
-		items, _ := List(ctx, cli, service)
-
-		if len(items) == 0 {
-			return false, nil
-		}
-
-		for _, item := range strings.Split(string(items), "\n") {
-			if strings.Contains(item, "The agent has no identities") {
-				return false, errors.New(item)
-			}
-			if strings.Contains(item, "Error loading key") {
-				return false, errors.New(item)
-			}
-			if strings.Contains(item, string(data)) {
-				result = true
-			}
-		}
+	// ssh-add exits with 1 when the agent has no identities.
+	if result.ExitCode > 1 {
+		return false, errors.New(strings.TrimSpace(string(result.Stderr)))
 	}
-	return result, nil
+	return HasFingerprint(ParseIdentities(string(result.Stdout)), key.Fingerprint()), nil
 }
@@ -1,11 +1,19 @@
 package agent_test
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
 	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/pygmystack/pygmy/internal/runtime/docker"
 	"github.com/pygmystack/pygmy/internal/runtime/docker/internals"
@@ -50,3 +58,301 @@ func Test(t *testing.T) {
 		So(obj.HostConfig.RestartPolicy.MaximumRetryCount, ShouldEqual, 0)
 	})
 }
+
+func TestParseIdentities(t *testing.T) {
+	Convey("SSH Agent: Identity parsing tests...", t, func() {
+		output := "3072 SHA256:5aKhzZo4/8gpRTzGm0SJvAbmMn7aNKCfQ3OIfzf2Rgs user@example.com (RSA)\n" +
+			"256 SHA256:GrsHXNSO3qS0iTaBjFyqcYtJn5TwxUb9MqvVDtT2kNQ work key (laptop) (ED25519)\n" +
+			"256 SHA256:0lTgBIYeg9fT3lzSHv5wOA3zZY5t2lBqcgMbj4ZxNfo (ECDSA)\n"
+
+		identities := agent.ParseIdentities(output)
+		So(identities, ShouldHaveLength, 3)
+		So(identities[0], ShouldResemble, agent.Identity{Bits: 3072, Fingerprint: "SHA256:5aKhzZo4/8gpRTzGm0SJvAbmMn7aNKCfQ3OIfzf2Rgs", Type: "RSA", Comment: "user@example.com"})
+		So(identities[1].Comment, ShouldEqual, "work key (laptop)")
+		So(identities[1].Type, ShouldEqual, "ED25519")
+		So(identities[2].Comment, ShouldBeEmpty)
+
+		So(agent.ParseIdentities("The agent has no identities.\n"), ShouldBeEmpty)
+	})
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	Convey("SSH Agent: Public key parsing tests...", t, func() {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		So(err, ShouldBeNil)
+		key, err := ssh.NewPublicKey(pub)
+		So(err, ShouldBeNil)
+		line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))) + " user@example.com"
+
+		keys := agent.ParsePublicKeys("The agent has no identities.\n" + line + "\n")
+		So(keys, ShouldHaveLength, 1)
+		So(keys[0].Key, ShouldEqual, line)
+		So(keys[0].Comment, ShouldEqual, "user@example.com")
+		So(keys[0].Fingerprint, ShouldEqual, ssh.FingerprintSHA256(key))
+		So(keys[0].Matches(ssh.FingerprintSHA256(key)), ShouldBeTrue)
+	})
+
+	Convey("SSH Agent: Certificate parsing tests...", t, func() {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		So(err, ShouldBeNil)
+		key, err := ssh.NewPublicKey(pub)
+		So(err, ShouldBeNil)
+		_, caKey, err := ed25519.GenerateKey(rand.Reader)
+		So(err, ShouldBeNil)
+		ca, err := ssh.NewSignerFromKey(caKey)
+		So(err, ShouldBeNil)
+		cert := &ssh.Certificate{
+			Key:             key,
+			CertType:        ssh.UserCert,
+			KeyId:           "user@example.com",
+			ValidPrincipals: []string{"user"},
+			ValidBefore:     ssh.CertTimeInfinity,
+		}
+		So(cert.SignCert(rand.Reader, ca), ShouldBeNil)
+		line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))) + " user@example.com"
+
+		keys := agent.ParsePublicKeys(line + "\n")
+		So(keys, ShouldHaveLength, 1)
+		So(keys[0].Fingerprint, ShouldEqual, ssh.FingerprintSHA256(cert))
+		So(keys[0].KeyFingerprint, ShouldEqual, ssh.FingerprintSHA256(key))
+		So(keys[0].Matches(ssh.FingerprintSHA256(key)), ShouldBeTrue)
+		So(keys[0].Matches(ssh.FingerprintSHA256(cert)), ShouldBeTrue)
+	})
+}
+
+// writeKey will write a new ed25519 private key to dir/name, encrypted
+// when a passphrase is given, and return its public key.
+func writeKey(dir string, name string, passphrase string) (ssh.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	So(err, ShouldBeNil)
+	key, err := ssh.NewPublicKey(pub)
+	So(err, ShouldBeNil)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	So(err, ShouldBeNil)
+	So(os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600), ShouldBeNil)
+	return key, priv
+}
+
+func TestLoadKey(t *testing.T) {
+	Convey("SSH Agent: Key loading tests...", t, func() {
+		dir := t.TempDir()
+
+		Convey("from the private key", func() {
+			key, _ := writeKey(dir, "id_ed25519", "")
+			k, err := agent.LoadKey(filepath.Join(dir, "id_ed25519"))
+			So(err, ShouldBeNil)
+			So(k.Encrypted, ShouldBeFalse)
+			So(k.Fingerprint(), ShouldEqual, ssh.FingerprintSHA256(key))
+			So(k.Type(), ShouldEqual, ssh.KeyAlgoED25519)
+		})
+
+		Convey("from the public key, with a name ending in pub", func() {
+			key, _ := writeKey(dir, "id_pub", "")
+			So(os.WriteFile(filepath.Join(dir, "id_pub.pub"), ssh.MarshalAuthorizedKey(key), 0644), ShouldBeNil)
+			k, err := agent.LoadKey(filepath.Join(dir, "id_pub.pub"))
+			So(err, ShouldBeNil)
+			So(k.Path, ShouldEqual, filepath.Join(dir, "id_pub"))
+			So(k.Fingerprint(), ShouldEqual, ssh.FingerprintSHA256(key))
+		})
+
+		Convey("which is passphrase protected", func() {
+			key, _ := writeKey(dir, "id_ed25519", "secret")
+			k, err := agent.LoadKey(filepath.Join(dir, "id_ed25519"))
+			So(err, ShouldBeNil)
+			So(k.Encrypted, ShouldBeTrue)
+			So(k.Fingerprint(), ShouldEqual, ssh.FingerprintSHA256(key))
+		})
+
+		Convey("with a certificate", func() {
+			key, _ := writeKey(dir, "id_ed25519", "")
+			_, ca, err := ed25519.GenerateKey(rand.Reader)
+			So(err, ShouldBeNil)
+			signer, err := ssh.NewSignerFromKey(ca)
+			So(err, ShouldBeNil)
+			cert := &ssh.Certificate{Key: key, CertType: ssh.UserCert, ValidPrincipals: []string{"user"}, ValidBefore: ssh.CertTimeInfinity}
+			So(cert.SignCert(rand.Reader, signer), ShouldBeNil)
+			So(os.WriteFile(filepath.Join(dir, "id_ed25519-cert.pub"), ssh.MarshalAuthorizedKey(cert), 0644), ShouldBeNil)
+
+			k, err := agent.LoadKey(filepath.Join(dir, "id_ed25519-cert.pub"))
+			So(err, ShouldBeNil)
+			So(k.Path, ShouldEqual, filepath.Join(dir, "id_ed25519"))
+			So(k.Certificate, ShouldNotBeNil)
+			So(k.Fingerprint(), ShouldEqual, ssh.FingerprintSHA256(key))
+		})
+
+		Convey("held by a security key", func() {
+			pub, _, err := ed25519.GenerateKey(rand.Reader)
+			So(err, ShouldBeNil)
+			blob := ssh.Marshal(struct {
+				Name        string
+				KeyBytes    []byte
+				Application string
+			}{ssh.KeyAlgoSKED25519, pub, "ssh:"})
+			envelope := ssh.Marshal(struct {
+				CipherName   string
+				KdfName      string
+				KdfOpts      string
+				NumKeys      uint32
+				PubKey       []byte
+				PrivKeyBlock []byte
+			}{"none", "none", "", 1, blob, []byte("key handle")})
+			data := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: append([]byte("openssh-key-v1\x00"), envelope...)})
+			So(os.WriteFile(filepath.Join(dir, "id_ed25519_sk"), data, 0600), ShouldBeNil)
+
+			k, err := agent.LoadKey(filepath.Join(dir, "id_ed25519_sk"))
+			So(err, ShouldBeNil)
+			So(k.SecurityKey(), ShouldBeTrue)
+			So(k.Type(), ShouldEqual, ssh.KeyAlgoSKED25519)
+		})
+
+		Convey("with a public key which doesn't match", func() {
+			writeKey(dir, "id_ed25519", "")
+			other, _ := writeKey(dir, "other", "")
+			So(os.WriteFile(filepath.Join(dir, "id_ed25519.pub"), ssh.MarshalAuthorizedKey(other), 0644), ShouldBeNil)
+			_, err := agent.LoadKey(filepath.Join(dir, "id_ed25519"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("which is not a key", func() {
+			So(os.WriteFile(filepath.Join(dir, "id_ed25519"), []byte("not a key"), 0600), ShouldBeNil)
+			_, err := agent.LoadKey(filepath.Join(dir, "id_ed25519"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("which doesn't exist", func() {
+			_, err := agent.Fingerprint(filepath.Join(dir, "missing"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestHasFingerprint(t *testing.T) {
+	Convey("SSH Agent: Fingerprint search tests...", t, func() {
+		identities := agent.ParseIdentities("256 SHA256:GrsHXNSO3qS0iTaBjFyqcYtJn5TwxUb9MqvVDtT2kNQ user@example.com (ED25519)\n")
+		So(agent.HasFingerprint(identities, "SHA256:GrsHXNSO3qS0iTaBjFyqcYtJn5TwxUb9MqvVDtT2kNQ"), ShouldBeTrue)
+		So(agent.HasFingerprint(identities, "SHA256:GrsHXNSO3qS0iTaBjFyqcYtJn5TwxUb9MqvVDtT2kN"), ShouldBeFalse)
+	})
+}
+
+func TestStdinInput(t *testing.T) {
+	Convey("SSH Agent: Key input tests...", t, func() {
+		dir := t.TempDir()
+
+		Convey("with an encrypted key, which stays encrypted", func() {
+			writeKey(dir, "id_ed25519", "secret")
+			k, err := agent.LoadKey(filepath.Join(dir, "id_ed25519"))
+			So(err, ShouldBeNil)
+			data, err := os.ReadFile(k.Path)
+			So(err, ShouldBeNil)
+
+			input, err := agent.StdinInput(k, []byte("secret"), "")
+			So(err, ShouldBeNil)
+			So(string(input), ShouldEqual, "secret\n"+string(data))
+
+			_, err = agent.StdinInput(k, []byte("wrong"), "")
+			So(err, ShouldEqual, agent.ErrIncorrectPassphrase)
+
+			_, err = agent.StdinInput(k, []byte("secret\nssh-add"), "")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("with an unencrypted key and a comment", func() {
+			key, _ := writeKey(dir, "id_plain", "")
+			k, err := agent.LoadKey(filepath.Join(dir, "id_plain"))
+			So(err, ShouldBeNil)
+
+			input, err := agent.StdinInput(k, nil, "user@example.com")
+			So(err, ShouldBeNil)
+			So(input[0], ShouldEqual, byte('\n'))
+			raw, err := ssh.ParseRawPrivateKey(input[1:])
+			So(err, ShouldBeNil)
+			signer, err := ssh.NewSignerFromKey(raw)
+			So(err, ShouldBeNil)
+			So(ssh.FingerprintSHA256(signer.PublicKey()), ShouldEqual, ssh.FingerprintSHA256(key))
+		})
+
+		Convey("is read by the ssh-add command", func() {
+			cmd := agent.StdinCommand("-t", "60")
+			So(cmd[:2], ShouldResemble, []string{"sh", "-c"})
+			So(cmd[2], ShouldContainSubstring, "SSH_ASKPASS_REQUIRE=force")
+			So(cmd[3:], ShouldResemble, []string{"ssh-add", "-t", "60"})
+		})
+	})
+}
+
+func TestAskpass(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the askpass program is a shell script")
+	}
+	Convey("SSH Agent: Askpass tests...", t, func() {
+		program := filepath.Join(t.TempDir(), "askpass")
+		So(os.WriteFile(program, []byte("#!/bin/sh\necho secret\n"), 0700), ShouldBeNil)
+		t.Setenv("SSH_ASKPASS", program)
+		t.Setenv("SSH_ASKPASS_REQUIRE", "")
+
+		Convey("is used without a terminal", func() {
+			passphrase, ok, err := agent.Askpass("Enter passphrase: ", false)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(string(passphrase), ShouldEqual, "secret")
+		})
+
+		Convey("is not used with a terminal unless preferred", func() {
+			_, ok, _ := agent.Askpass("Enter passphrase: ", true)
+			So(ok, ShouldBeFalse)
+
+			t.Setenv("SSH_ASKPASS_REQUIRE", "prefer")
+			_, ok, _ = agent.Askpass("Enter passphrase: ", true)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("is never used when disabled", func() {
+			t.Setenv("SSH_ASKPASS_REQUIRE", "never")
+			_, ok, _ := agent.Askpass("Enter passphrase: ", false)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestForwarder(t *testing.T) {
+	Convey("SSH Agent: Forwarder tests...", t, func() {
+		Convey("proxies the host socket in place of the agent", func() {
+			f := agent.NewForwarder("/tmp/agent.sock", "")
+			So(f.Config.Image, ShouldEqual, agent.DefaultForwarderImage)
+			So(f.Config.Labels["pygmy.agent.mode"], ShouldEqual, "forward")
+			So(f.Config.Labels["pygmy.name"], ShouldEqual, agent.New().Config.Labels["pygmy.name"])
+			So([]string(f.Config.Entrypoint), ShouldResemble, []string{"socat"})
+			So(f.Config.Cmd, ShouldHaveLength, 2)
+			So(f.Config.Cmd[0], ShouldStartWith, "UNIX-LISTEN:"+agent.Socket+",")
+			So(f.Config.Volumes, ShouldContainKey, agent.SocketDir)
+			So(f.HostConfig.Binds, ShouldHaveLength, 1)
+			So(f.HostConfig.Binds[0], ShouldStartWith, "/tmp/agent.sock:")
+		})
+
+		Convey("uses the given image", func() {
+			f := agent.NewForwarder("", "example/socat")
+			So(f.Config.Image, ShouldEqual, "example/socat")
+			So(f.HostConfig.Binds, ShouldBeEmpty)
+		})
+
+		Convey("finds the host socket", func() {
+			if runtime.GOOS == "darwin" {
+				socket, err := agent.HostSocket()
+				So(err, ShouldBeNil)
+				So(socket, ShouldEqual, "/run/host-services/ssh-auth.sock")
+				return
+			}
+			t.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+			socket, err := agent.HostSocket()
+			So(err, ShouldBeNil)
+			So(socket, ShouldEqual, "/tmp/agent.sock")
+
+			t.Setenv("SSH_AUTH_SOCK", "")
+			_, err = agent.HostSocket()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
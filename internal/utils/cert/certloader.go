@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	aur "github.com/logrusorgru/aurora"
 	"github.com/mitchellh/go-homedir"
@@ -65,6 +66,28 @@ func ResolveCertPath(flagCertPath string) (string, error) {
 	return "", nil
 }
 
+// Expiry will return the time the first certificate in a .pem expires.
+func Expiry(certPath string) (time.Time, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return time.Time{}, fmt.Errorf("no certificates found in %s", certPath)
+		}
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse certificate: %w", err)
+			}
+			return cert.NotAfter, nil
+		}
+	}
+}
+
 // verifyCertificate will take a .pem and verify it is appropriate for use with HAProxy.
 func verifyCertificate(certPath string) error {
 	data, err := os.ReadFile(certPath)
@@ -1,68 +1,252 @@
-// Package endpoint provides a way to test a HTTP/HTTPS endpoint for a 200 response code.
-// note that this package does not support insecure HTTPS at this time.
+// Package endpoint provides a way to test a HTTP/HTTPS endpoint and report
+// its status code, latency, redirects and TLS certificate.
 package endpoint
 
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DefaultTimeout is used when Options has no Timeout.
+const DefaultTimeout = 10 * time.Second
+
+// DefaultExpect is the range of status codes which pass when Options has
+// no Expect.
+var DefaultExpect = []StatusRange{{Min: 200, Max: 399}}
+
+// maxRedirects is the number of redirects which will be followed.
+const maxRedirects = 10
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// Contains will report if a status code is within the range.
+func (r StatusRange) Contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+// ParseExpect will parse a comma separated list of status codes and
+// ranges, such as "200-299,301,401".
+func ParseExpect(s string) ([]StatusRange, error) {
+	var ranges []StatusRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lower, upper, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(lower))
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(upper)); err != nil || to < from {
+				return nil, fmt.Errorf("invalid status code range %q", part)
+			}
+		}
+		ranges = append(ranges, StatusRange{Min: from, Max: to})
+	}
+	return ranges, nil
+}
+
+// Options changes how an endpoint is checked.
+type Options struct {
+	// Timeout is the maximum time to wait for a response.
+	Timeout time.Duration
+	// Expect are the status codes which are considered a success.
+	Expect []StatusRange
+	// RootCertPaths are PEM files with certificates which will be trusted
+	// in addition to the system roots, such as the pygmy certificate.
+	RootCertPaths []string
+	// Address is connected to instead of the address the host of the
+	// endpoint resolves to, such as the host of a remote Docker daemon.
+	// The host name is still used for the request and TLS.
+	Address string
+}
+
+// Result is the outcome of a request to an endpoint.
+type Result struct {
+	// URL is the endpoint which was requested.
+	URL string
+	// Method is the HTTP method of the final request. HEAD is used unless
+	// the endpoint doesn't support it.
+	Method string
+	// StatusCode is the response status code, or zero if no response was
+	// received.
+	StatusCode int
+	// Expected reports if StatusCode is one of the expected status codes.
+	Expected bool
+	// Latency is the time taken to receive the response.
+	Latency time.Duration
+	// Redirects are the URLs which were redirected to, in order.
+	Redirects []string
+	// TLSError is the reason the certificate could not be verified. The
+	// request is repeated without verification to report the status.
+	TLSError error
+	// CertificateIssuer is the issuer of the certificate presented.
+	CertificateIssuer string
+	// CertificateExpiry is when the certificate presented expires.
+	CertificateExpiry time.Time
+	// Err is the reason no response was received.
+	Err error
+}
+
+// OK will report if the endpoint responded with an expected status code
+// and, for HTTPS, a certificate which could be verified.
+func (r Result) OK() bool {
+	return r.Err == nil && r.TLSError == nil && r.Expected
+}
+
 // Validate will submit a web request to test the container service.
-// If a 200 response code is received it will pass and return true.
-// Any other result will fail this validation process.
+// If a 2xx or 3xx response code is received it will pass and return
+// true. Any other result will fail this validation process.
 //
 // This is to provided to the user through the up and status commands.
 func Validate(url string) bool {
-	var timeout = time.Second * 10
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	return Check(url, Options{}).OK()
+}
 
-	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-			DisableKeepAlives: true,
-		},
+// Check will submit a web request to test the container service and
+// report the details of the response.
+func Check(url string, opts Options) Result {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if len(opts.Expect) == 0 {
+		opts.Expect = DefaultExpect
+	}
+
+	result := Result{URL: url}
+
+	roots, err := rootCAs(opts.RootCertPaths)
+	if err != nil {
+		result.Err = err
+		return result
 	}
 
-	if strings.HasPrefix(url, "https://") {
-		client.Transport = &http.Transport{
-			TLSClientConfig:     &tls.Config{InsecureSkipVerify: false},
-			DisableKeepAlives:   true,
-			TLSHandshakeTimeout: timeout,
+	result = request(url, opts, &tls.Config{RootCAs: roots})
+
+	// Report the status of endpoints with certificates which can't be
+	// verified, as well as the verification error.
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(result.Err, &verifyErr) {
+		result = request(url, opts, &tls.Config{InsecureSkipVerify: true})
+		result.TLSError = verifyErr.Err
+	}
+
+	for _, r := range opts.Expect {
+		if r.Contains(result.StatusCode) {
+			result.Expected = true
 		}
 	}
 
-	// Create a web request using HEAD for faster response
-	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
-	if err != nil {
-		// Test failed.
-		return false
+	return result
+}
+
+// request will request the endpoint with HEAD, and with GET when HEAD is
+// not supported.
+func request(url string, opts Options, tlsConfig *tls.Config) Result {
+	result := Result{URL: url}
+
+	transport := &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		DisableKeepAlives:   true,
+		TLSHandshakeTimeout: opts.Timeout,
+	}
+	if opts.Address != "" {
+		dialer := &net.Dialer{Timeout: opts.Timeout}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(opts.Address, port))
+		}
 	}
 
-	// Submit a web request
-	resp, err := client.Do(req)
-	if err != nil {
-		// Test failed.
-		return false
+	client := &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			result.Redirects = append(result.Redirects, req.URL.String())
+			return nil
+		},
 	}
 
-	// Housekeeping
-	defer func() { _ = resp.Body.Close() }()
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		// The context of each attempt is cancelled once its response has
+		// been read, rather than when all attempts are done.
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			cancel()
+			result.Err = err
+			return result
+		}
+
+		result.Method = method
+		result.Redirects = nil
+		start := time.Now()
+		resp, err := client.Do(req)
+		result.Latency = time.Since(start)
+		if err != nil {
+			cancel()
+			result.Err = err
+			return result
+		}
+		_ = resp.Body.Close()
+		cancel()
 
-	// The default response for a failed loopback is a 503.
-	// Because server errors are 503, we should make sure
-	// we do not get a 5xx response code from the endpoint.
-	// 500 is known to be a success as well, so start from 501.
+		result.StatusCode = resp.StatusCode
+		if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+			certificate := resp.TLS.PeerCertificates[0]
+			result.CertificateIssuer = certificate.Issuer.String()
+			result.CertificateExpiry = certificate.NotAfter
+		}
 
-	// Check for known failure status response codes (failures):
-	if resp.StatusCode >= 501 && resp.StatusCode < 600 {
-		return false
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
 	}
 
-	// Test passed.
-	return true
+	return result
+}
+
+// rootCAs will return the system roots with the certificates in paths
+// added, or nil to use the system roots when there are none.
+func rootCAs(paths []string) (*x509.CertPool, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", path)
+		}
+	}
+	return pool, nil
 }
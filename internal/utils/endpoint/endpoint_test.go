@@ -1,6 +1,12 @@
 package endpoint_test
 
 import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -18,3 +24,98 @@ func Test(t *testing.T) {
 		So(valid, ShouldBeTrue)
 	})
 }
+
+func TestCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer healthy.Close()
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	getOnly := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer getOnly.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(healthy.URL+"/target", http.StatusFound))
+	defer redirect.Close()
+
+	Convey("URL Endpoint check tests...", t, func() {
+		result := endpoint.Check(healthy.URL, endpoint.Options{})
+		So(result.Err, ShouldBeNil)
+		So(result.StatusCode, ShouldEqual, http.StatusNoContent)
+		So(result.Method, ShouldEqual, http.MethodHead)
+		So(result.Latency, ShouldBeGreaterThan, 0)
+		So(result.OK(), ShouldBeTrue)
+
+		result = endpoint.Check(unavailable.URL, endpoint.Options{})
+		So(result.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+		So(result.OK(), ShouldBeFalse)
+
+		result = endpoint.Check("http://127.0.0.1:0", endpoint.Options{})
+		So(result.Err, ShouldNotBeNil)
+		So(result.OK(), ShouldBeFalse)
+	})
+
+	Convey("URL Endpoint address tests...", t, func() {
+		u, err := url.Parse(healthy.URL)
+		So(err, ShouldBeNil)
+		address := "http://docker.invalid:" + u.Port()
+
+		So(endpoint.Check(address, endpoint.Options{}).OK(), ShouldBeFalse)
+		So(endpoint.Check(address, endpoint.Options{Address: u.Hostname()}).OK(), ShouldBeTrue)
+	})
+
+	Convey("URL Endpoint expectation tests...", t, func() {
+		So(endpoint.Check(missing.URL, endpoint.Options{}).OK(), ShouldBeFalse)
+
+		expect, err := endpoint.ParseExpect("200-299, 404")
+		So(err, ShouldBeNil)
+		So(expect, ShouldResemble, []endpoint.StatusRange{{Min: 200, Max: 299}, {Min: 404, Max: 404}})
+		So(endpoint.Check(missing.URL, endpoint.Options{Expect: expect}).OK(), ShouldBeTrue)
+
+		_, err = endpoint.ParseExpect("299-200")
+		So(err, ShouldNotBeNil)
+		_, err = endpoint.ParseExpect("ok")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("URL Endpoint fallback and redirect tests...", t, func() {
+		result := endpoint.Check(getOnly.URL, endpoint.Options{})
+		So(result.Method, ShouldEqual, http.MethodGet)
+		So(result.StatusCode, ShouldEqual, http.StatusOK)
+
+		result = endpoint.Check(redirect.URL, endpoint.Options{})
+		So(result.OK(), ShouldBeTrue)
+		So(result.Redirects, ShouldResemble, []string{healthy.URL + "/target"})
+	})
+}
+
+func TestCheckTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	certPath := filepath.Join(t.TempDir(), "server.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(certPath, certificate, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("URL Endpoint TLS tests...", t, func() {
+		result := endpoint.Check(server.URL, endpoint.Options{})
+		So(result.TLSError, ShouldNotBeNil)
+		So(result.StatusCode, ShouldEqual, http.StatusOK)
+		So(result.OK(), ShouldBeFalse)
+
+		result = endpoint.Check(server.URL, endpoint.Options{RootCertPaths: []string{certPath}})
+		So(result.TLSError, ShouldBeNil)
+		So(result.OK(), ShouldBeTrue)
+		So(result.CertificateIssuer, ShouldNotBeEmpty)
+		So(result.CertificateExpiry, ShouldEqual, server.Certificate().NotAfter)
+	})
+}
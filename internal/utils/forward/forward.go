@@ -0,0 +1,184 @@
+// Package forward forwards local ports to a remote Docker host over SSH,
+// so the services of a remote daemon can be reached on this machine. The
+// forwards are kept open by a background ssh process, which is managed
+// through its control socket.
+package forward
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pygmystack/pygmy/internal/utils/state"
+)
+
+// DefaultPorts are forwarded when none are configured: HTTP and HTTPS
+// through haproxy, and SMTP for mailhog.
+var DefaultPorts = []string{"80", "443", "1025"}
+
+// Port is a local port forwarded to a port of the remote host.
+type Port struct {
+	Local  int
+	Remote int
+}
+
+// String will format the port as it is configured.
+func (p Port) String() string {
+	if p.Local == p.Remote {
+		return strconv.Itoa(p.Local)
+	}
+	return fmt.Sprintf("%d:%d", p.Local, p.Remote)
+}
+
+// ParsePorts will parse ports given as "port", forwarding the same port,
+// or "local:remote".
+func ParsePorts(ports []string) ([]Port, error) {
+	parsed := make([]Port, 0, len(ports))
+	for _, port := range ports {
+		local, remote, ok := strings.Cut(port, ":")
+		if !ok {
+			remote = local
+		}
+		l, err := parsePort(local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forwarded port %q: %w", port, err)
+		}
+		r, err := parsePort(remote)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forwarded port %q: %w", port, err)
+		}
+		parsed = append(parsed, Port{Local: l, Remote: r})
+	}
+	return parsed, nil
+}
+
+// parsePort will parse a TCP port number.
+func parsePort(port string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("%q is not a port number", port)
+	}
+	return n, nil
+}
+
+// Forward is a set of local ports forwarded to a remote host over SSH.
+type Forward struct {
+	// Destination are the ssh arguments which identify the remote host.
+	Destination []string
+	// Ports are the ports forwarded from this machine.
+	Ports []Port
+	// Socket is the control socket of the ssh process.
+	Socket string
+}
+
+// New will create a Forward to destination, with its control socket next
+// to the state file.
+func New(destination []string, ports []Port) Forward {
+	return Forward{
+		Destination: destination,
+		Ports:       ports,
+		Socket:      filepath.Join(filepath.Dir(state.Path()), "forward.sock"),
+	}
+}
+
+// Args will return the arguments of the ssh process which holds the
+// forwards open. ssh returns once every forward is listening, and fails
+// if any of the local ports can't be bound.
+func (f Forward) Args() []string {
+	args := []string{
+		"-f", "-N", "-M",
+		"-S", f.Socket,
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=30",
+	}
+	for _, port := range f.Ports {
+		args = append(args, "-L", fmt.Sprintf("127.0.0.1:%d:127.0.0.1:%d", port.Local, port.Remote))
+	}
+	return append(args, f.Destination...)
+}
+
+// Start will open the forwards in a background ssh process. A control
+// socket left behind by an ssh process which has since died is removed
+// first, as ssh would otherwise run without becoming the master and the
+// forwards could not be stopped.
+func (f Forward) Start() error {
+	if err := os.MkdirAll(filepath.Dir(f.Socket), 0700); err != nil {
+		return err
+	}
+	if _, err := os.Stat(f.Socket); err == nil {
+		if f.control("check") == nil {
+			return fmt.Errorf("could not forward ports %v: ports are already forwarded through %v", f, f.Socket)
+		}
+		if err := os.Remove(f.Socket); err != nil {
+			return fmt.Errorf("could not remove the stale control socket %v: %w", f.Socket, err)
+		}
+	}
+
+	// The background ssh process inherits the output of the command, so
+	// it is written to a file rather than a pipe which would stay open.
+	output, err := os.CreateTemp("", "pygmy-forward-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = output.Close()
+		_ = os.Remove(output.Name())
+	}()
+
+	cmd := exec.Command("ssh", f.Args()...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
+		message, _ := os.ReadFile(output.Name())
+		return fmt.Errorf("could not forward ports %v: %s", f, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// Running will report if the ssh process holding the forwards is running.
+func (f Forward) Running() bool {
+	if _, err := os.Stat(f.Socket); err != nil {
+		return false
+	}
+	return f.control("check") == nil
+}
+
+// Stop will close the forwards by stopping the ssh process.
+func (f Forward) Stop() error {
+	if err := f.control("exit"); err != nil {
+		return fmt.Errorf("could not stop forwarding ports: %w", err)
+	}
+	return nil
+}
+
+// control will send a command to the ssh process through its control
+// socket.
+func (f Forward) control(command string) error {
+	args := append([]string{"-S", f.Socket, "-O", command}, f.Destination...)
+	output, err := exec.Command("ssh", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// String will list the forwarded ports for display.
+func (f Forward) String() string {
+	ports := make([]string, 0, len(f.Ports))
+	for _, port := range f.Ports {
+		ports = append(ports, port.String())
+	}
+	return strings.Join(ports, ", ")
+}
+
+// Host will return the remote host the ports are forwarded to.
+func (f Forward) Host() string {
+	if len(f.Destination) == 0 {
+		return ""
+	}
+	return f.Destination[len(f.Destination)-1]
+}
@@ -0,0 +1,63 @@
+package forward_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/pygmystack/pygmy/internal/utils/forward"
+)
+
+func Test(t *testing.T) {
+	Convey("Forward: Port tests...", t, func() {
+		ports, err := forward.ParsePorts(forward.DefaultPorts)
+		So(err, ShouldBeNil)
+		So(ports, ShouldResemble, []forward.Port{{Local: 80, Remote: 80}, {Local: 443, Remote: 443}, {Local: 1025, Remote: 1025}})
+
+		ports, err = forward.ParsePorts([]string{"8080:80"})
+		So(err, ShouldBeNil)
+		So(ports, ShouldResemble, []forward.Port{{Local: 8080, Remote: 80}})
+		So(ports[0].String(), ShouldEqual, "8080:80")
+
+		for _, invalid := range []string{"", "http", "0", "70000", "80:", ":80"} {
+			_, err = forward.ParsePorts([]string{invalid})
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Forward: Argument tests...", t, func() {
+		f := forward.Forward{
+			Destination: []string{"-l", "docker", "vm.example.com"},
+			Ports:       []forward.Port{{Local: 8080, Remote: 80}, {Local: 443, Remote: 443}},
+			Socket:      "/tmp/forward.sock",
+		}
+		So(f.Args(), ShouldResemble, []string{
+			"-f", "-N", "-M",
+			"-S", "/tmp/forward.sock",
+			"-o", "ExitOnForwardFailure=yes",
+			"-o", "ServerAliveInterval=30",
+			"-L", "127.0.0.1:8080:127.0.0.1:80",
+			"-L", "127.0.0.1:443:127.0.0.1:443",
+			"-l", "docker", "vm.example.com",
+		})
+		So(f.String(), ShouldEqual, "8080:80, 443")
+		So(f.Running(), ShouldBeFalse)
+	})
+
+	Convey("Forward: Stale socket tests...", t, func() {
+		f := forward.Forward{
+			Destination: []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=1", "-p", "1", "127.0.0.1"},
+			Ports:       []forward.Port{{Local: 8080, Remote: 80}},
+			Socket:      filepath.Join(t.TempDir(), "forward.sock"),
+		}
+		So(os.WriteFile(f.Socket, nil, 0600), ShouldBeNil)
+		So(f.Running(), ShouldBeFalse)
+
+		// ssh can't connect, but the socket of the dead process is gone.
+		So(f.Start(), ShouldNotBeNil)
+		_, err := os.Stat(f.Socket)
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+}
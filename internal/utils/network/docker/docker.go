@@ -1,9 +1,18 @@
 package docker
 
 import (
+	"strings"
+
 	networktypes "github.com/docker/docker/api/types/network"
 )
 
+// IPv6Subnet is the unique local subnet used for the Docker network when
+// IPv6 is enabled.
+const IPv6Subnet = "fd00:99:99:99::/64"
+
+// IPv6Gateway is the gateway of IPv6Subnet.
+const IPv6Gateway = "fd00:99:99:99::1"
+
 // New will generate the defaults for the Docker network.
 // If configuration is provided this will not be used at all.
 func New() networktypes.Inspect {
@@ -24,3 +33,19 @@ func New() networktypes.Inspect {
 		},
 	}
 }
+
+// WithIPv6 will enable IPv6 on a network, adding the default IPv6 subnet
+// unless an IPv6 subnet is already configured.
+func WithIPv6(network networktypes.Inspect) networktypes.Inspect {
+	network.EnableIPv6 = true
+	for _, config := range network.IPAM.Config {
+		if strings.Contains(config.Subnet, ":") {
+			return network
+		}
+	}
+	network.IPAM.Config = append(append([]networktypes.IPAMConfig{}, network.IPAM.Config...), networktypes.IPAMConfig{
+		Subnet:  IPv6Subnet,
+		Gateway: IPv6Gateway,
+	})
+	return network
+}
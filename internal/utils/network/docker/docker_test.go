@@ -22,4 +22,11 @@ func Test(t *testing.T) {
 		So(fmt.Sprint(obj.IPAM.Config), ShouldEqual, fmt.Sprint([]network.IPAMConfig{{Subnet: "10.99.99.0/24", Gateway: "10.99.99.1"}}))
 		So(fmt.Sprint(obj.Labels), ShouldEqual, fmt.Sprint(map[string]string{"pygmy.name": "amazeeio-network"}))
 	})
+
+	Convey("Network: IPv6 tests...", t, func() {
+		obj := n.WithIPv6(n.New())
+		So(obj.EnableIPv6, ShouldBeTrue)
+		So(fmt.Sprint(obj.IPAM.Config), ShouldEqual, fmt.Sprint([]network.IPAMConfig{{Subnet: "10.99.99.0/24", Gateway: "10.99.99.1"}, {Subnet: n.IPv6Subnet, Gateway: n.IPv6Gateway}}))
+		So(n.WithIPv6(obj).IPAM.Config, ShouldHaveLength, 2)
+	})
 }
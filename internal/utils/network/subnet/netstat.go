@@ -0,0 +1,60 @@
+package subnet
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// parseNetstat will parse the IPv4 routing table printed by the BSD
+// netstat -rn, as on macOS. Destinations leave out trailing zero octets,
+// and their prefix length when it covers the octets given, so 10 is
+// 10.0.0.0/8 and 172.16/12 is 172.16.0.0/12.
+func parseNetstat(scanner *bufio.Scanner) ([]Used, error) {
+	var used []Used
+	header := true
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if header {
+			header = len(fields) == 0 || fields[0] != "Destination"
+			continue
+		}
+		if len(fields) < 4 || fields[0] == "default" {
+			continue
+		}
+		network, err := parseDestination(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		used = append(used, Used{Network: network, Source: fmt.Sprintf("route via %s", fields[3])})
+	}
+	return used, scanner.Err()
+}
+
+// parseDestination will parse a destination printed by the BSD netstat.
+func parseDestination(destination string) (*net.IPNet, error) {
+	address, length, hasLength := strings.Cut(destination, "/")
+	octets := strings.Split(address, ".")
+	if len(octets) > 4 {
+		return nil, fmt.Errorf("invalid destination %q in routing table", destination)
+	}
+	ones := len(octets) * 8
+	for len(octets) < 4 {
+		octets = append(octets, "0")
+	}
+	ip := net.ParseIP(strings.Join(octets, ".")).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid destination %q in routing table", destination)
+	}
+	if hasLength {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 || n > 32 {
+			return nil, fmt.Errorf("invalid destination %q in routing table", destination)
+		}
+		ones = n
+	}
+	mask := net.CIDRMask(ones, 32)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
@@ -0,0 +1,51 @@
+package subnet
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseNetstat(t *testing.T) {
+	Convey("Subnet: netstat routing table tests...", t, func() {
+		// Captured from netstat -rn -f inet on macOS, connected to a VPN
+		// which routes 10.0.0.0/8 and 172.16.0.0/12 through utun3.
+		table := `Routing tables
+
+Internet:
+Destination        Gateway            Flags               Netif Expire
+default            192.168.1.1        UGScg                 en0       
+10                 link#22            UCS                 utun3       
+10.8.0.1           10.8.0.5           UH                  utun3       
+127                127.0.0.1          UCS                   lo0       
+127.0.0.1          127.0.0.1          UH                    lo0       
+169.254            link#6             UCS                   en0      !
+172.16/12          10.8.0.1           UGSc                utun3       
+192.168.1          link#6             UCS                   en0      !
+192.168.1.1/32     link#6             UCS                   en0      !
+192.168.1.1        a4:91:b1:2c:3d:4e  UHLWIir               en0   1186
+`
+		routes, err := parseNetstat(bufio.NewScanner(strings.NewReader(table)))
+		So(err, ShouldBeNil)
+		var described []string
+		for _, route := range routes {
+			described = append(described, route.String())
+		}
+		So(described, ShouldResemble, []string{
+			"10.0.0.0/8 (route via utun3)",
+			"10.8.0.1/32 (route via utun3)",
+			"127.0.0.0/8 (route via lo0)",
+			"127.0.0.1/32 (route via lo0)",
+			"169.254.0.0/16 (route via en0)",
+			"172.16.0.0/12 (route via utun3)",
+			"192.168.1.0/24 (route via en0)",
+			"192.168.1.1/32 (route via en0)",
+			"192.168.1.1/32 (route via en0)",
+		})
+
+		_, err = parseNetstat(bufio.NewScanner(strings.NewReader("Destination Gateway Flags Netif\n10/40 link#22 UCS utun3\n")))
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,20 @@
+//go:build darwin
+// +build darwin
+
+package subnet
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+)
+
+// routes will read the IPv4 routing table with netstat, which includes
+// the routes VPN clients add through their utun devices.
+func routes() ([]Used, error) {
+	output, err := exec.Command("netstat", "-rn", "-f", "inet").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseNetstat(bufio.NewScanner(bytes.NewReader(output)))
+}
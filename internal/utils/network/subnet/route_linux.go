@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package subnet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// routes will read the IPv4 routing table from /proc/net/route, which
+// includes the routes added by VPN clients.
+func routes() ([]Used, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return parseRoutes(bufio.NewScanner(file))
+}
+
+// parseRoutes will parse the content of /proc/net/route. Addresses are
+// hex encoded in host byte order.
+func parseRoutes(scanner *bufio.Scanner) ([]Used, error) {
+	var used []Used
+	for header := true; scanner.Scan(); header = false {
+		if header {
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		destination, err := parseHex(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		mask, err := parseHex(fields[7])
+		if err != nil {
+			return nil, err
+		}
+		network := &net.IPNet{IP: destination, Mask: net.IPMask(mask)}
+		if ones, _ := network.Mask.Size(); ones == 0 {
+			continue
+		}
+		used = append(used, Used{Network: network, Source: fmt.Sprintf("route via %s", fields[0])})
+	}
+	return used, scanner.Err()
+}
+
+// parseHex will decode a little-endian hex encoded IPv4 address.
+func parseHex(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return nil, fmt.Errorf("invalid address %q in routing table", s)
+	}
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(b))
+	return ip, nil
+}
@@ -0,0 +1,27 @@
+//go:build linux
+// +build linux
+
+package subnet
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseRoutes(t *testing.T) {
+	Convey("Subnet: Routing table tests...", t, func() {
+		table := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0100A8C0	0003	0	0	100	00000000	0	0	0
+tun0	0000000A	00000000	0001	0	0	0	000000FF	0	0	0
+eth0	0000A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+`
+		routes, err := parseRoutes(bufio.NewScanner(strings.NewReader(table)))
+		So(err, ShouldBeNil)
+		So(routes, ShouldHaveLength, 2)
+		So(routes[0].String(), ShouldEqual, "10.0.0.0/8 (route via tun0)")
+		So(routes[1].String(), ShouldEqual, "192.168.0.0/24 (route via eth0)")
+	})
+}
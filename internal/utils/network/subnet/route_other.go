@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package subnet
+
+// routes is not implemented outside of Linux and macOS, where the
+// addresses of the host interfaces (including VPN tunnels) are used
+// instead.
+func routes() ([]Used, error) {
+	return nil, nil
+}
@@ -0,0 +1,130 @@
+// Package subnet detects conflicts between a Docker network subnet and the
+// networks already in use on the host, such as VPN routes or other Docker
+// networks, and selects a free subnet from a pool when asked to.
+package subnet
+
+import (
+	"fmt"
+	"net"
+)
+
+// Used is a network which is already in use, along with where it is in
+// use so conflicts can be explained to the user.
+type Used struct {
+	Network *net.IPNet
+	Source  string
+}
+
+// String will describe the network in use.
+func (u Used) String() string {
+	return fmt.Sprintf("%s (%s)", u.Network, u.Source)
+}
+
+// DefaultPool is the list of private ranges automatic selection will
+// choose a /24 from when no pool is configured.
+var DefaultPool = []string{
+	"10.99.0.0/16",
+	"172.30.0.0/16",
+	"192.168.224.0/20",
+}
+
+// Overlaps will report if two networks share any address.
+func Overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// Conflicts will return the networks in use which overlap subnet.
+func Conflicts(subnet *net.IPNet, used []Used) []Used {
+	var conflicts []Used
+	for _, u := range used {
+		if Overlaps(subnet, u.Network) {
+			conflicts = append(conflicts, u)
+		}
+	}
+	return conflicts
+}
+
+// Host will return the networks in use by the host, from the addresses
+// of its interfaces and, where available, its routing table. Default
+// routes are ignored as they overlap everything.
+func Host() ([]Used, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var used []Used
+	for _, i := range interfaces {
+		if i.Flags&net.FlagUp == 0 || i.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := i.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				_, network, _ := net.ParseCIDR(ipnet.String())
+				used = append(used, Used{Network: network, Source: fmt.Sprintf("interface %s", i.Name)})
+			}
+		}
+	}
+
+	routes, err := routes()
+	if err != nil {
+		return used, err
+	}
+	return append(used, routes...), nil
+}
+
+// Select will return the first /24 from the pool which doesn't overlap
+// any network in use.
+func Select(pool []string, used []Used) (*net.IPNet, error) {
+	if len(pool) == 0 {
+		pool = DefaultPool
+	}
+	for _, cidr := range pool {
+		_, block, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet pool entry %q: %w", cidr, err)
+		}
+		base := block.IP.To4()
+		if base == nil {
+			return nil, fmt.Errorf("subnet pool entry %q is not an IPv4 range", cidr)
+		}
+		if ones, _ := block.Mask.Size(); ones > 24 {
+			return nil, fmt.Errorf("subnet pool entry %q is smaller than a /24", cidr)
+		}
+
+		for ip := base; block.Contains(ip); ip = next(ip) {
+			candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(24, 32)}
+			if len(Conflicts(candidate, used)) == 0 {
+				return candidate, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no free /24 subnet is available in %v", pool)
+}
+
+// Gateway will return the first usable address of a subnet, which is
+// the address Docker would choose for the gateway.
+func Gateway(subnet *net.IPNet) net.IP {
+	ip := make(net.IP, len(subnet.IP.To4()))
+	copy(ip, subnet.IP.To4())
+	ip[len(ip)-1]++
+	return ip
+}
+
+// next will return the network address of the /24 following ip.
+func next(ip net.IP) net.IP {
+	n := make(net.IP, 4)
+	copy(n, ip)
+	for i := 2; i >= 0; i-- {
+		n[i]++
+		if n[i] != 0 {
+			return n
+		}
+	}
+	// Wrapped around the address space, return an address no block contains.
+	return net.IPv4bcast.To4()
+}
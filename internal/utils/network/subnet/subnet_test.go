@@ -0,0 +1,44 @@
+package subnet_test
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/pygmystack/pygmy/internal/utils/network/subnet"
+)
+
+func cidr(s string) *net.IPNet {
+	_, n, _ := net.ParseCIDR(s)
+	return n
+}
+
+func Test(t *testing.T) {
+	vpn := subnet.Used{Network: cidr("10.0.0.0/8"), Source: "route via tun0"}
+	docker := subnet.Used{Network: cidr("172.30.0.0/24"), Source: "docker network bridge"}
+
+	Convey("Subnet: Overlap tests...", t, func() {
+		So(subnet.Overlaps(cidr("10.99.99.0/24"), cidr("10.0.0.0/8")), ShouldBeTrue)
+		So(subnet.Overlaps(cidr("10.0.0.0/8"), cidr("10.99.99.0/24")), ShouldBeTrue)
+		So(subnet.Overlaps(cidr("10.99.99.0/24"), cidr("10.99.98.0/24")), ShouldBeFalse)
+		So(subnet.Conflicts(cidr("10.99.99.0/24"), []subnet.Used{vpn, docker}), ShouldResemble, []subnet.Used{vpn})
+		So(vpn.String(), ShouldEqual, "10.0.0.0/8 (route via tun0)")
+	})
+
+	Convey("Subnet: Selection tests...", t, func() {
+		selected, err := subnet.Select(nil, []subnet.Used{vpn, docker})
+		So(err, ShouldBeNil)
+		So(selected.String(), ShouldEqual, "172.30.1.0/24")
+		So(subnet.Gateway(selected).String(), ShouldEqual, "172.30.1.1")
+
+		_, err = subnet.Select([]string{"10.1.0.0/16"}, []subnet.Used{vpn})
+		So(err, ShouldNotBeNil)
+
+		_, err = subnet.Select([]string{"10.1.0.0/28"}, nil)
+		So(err, ShouldNotBeNil)
+
+		_, err = subnet.Select([]string{"not-a-subnet"}, nil)
+		So(err, ShouldNotBeNil)
+	})
+}
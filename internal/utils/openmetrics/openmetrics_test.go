@@ -0,0 +1,35 @@
+package openmetrics_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/pygmystack/pygmy/internal/utils/openmetrics"
+)
+
+func Test(t *testing.T) {
+	Convey("OpenMetrics: Output tests...", t, func() {
+		up := openmetrics.Family{Name: "pygmy_service_up", Type: openmetrics.Gauge, Help: "Whether the service is running."}
+		up.Add(openmetrics.Bool(true), openmetrics.Labels{"service": "amazeeio-haproxy", "image": "pygmystack/haproxy"})
+		up.Add(openmetrics.Bool(false), openmetrics.Labels{"service": `odd "name"`})
+		restarts := openmetrics.Family{Name: "pygmy_service_restarts", Type: openmetrics.Counter}
+		restarts.Add(3, openmetrics.Labels{"service": "amazeeio-haproxy"})
+		latency := openmetrics.Family{Name: "pygmy_route_latency_seconds", Type: openmetrics.Gauge}
+		latency.Add(0.25, nil)
+
+		var b bytes.Buffer
+		So(openmetrics.Write(&b, []openmetrics.Family{up, restarts, latency}), ShouldBeNil)
+		So(b.String(), ShouldEqual, `# TYPE pygmy_service_up gauge
+# HELP pygmy_service_up Whether the service is running.
+pygmy_service_up{image="pygmystack/haproxy",service="amazeeio-haproxy"} 1
+pygmy_service_up{service="odd \"name\""} 0
+# TYPE pygmy_service_restarts counter
+pygmy_service_restarts_total{service="amazeeio-haproxy"} 3
+# TYPE pygmy_route_latency_seconds gauge
+pygmy_route_latency_seconds 0.25
+# EOF
+`)
+	})
+}
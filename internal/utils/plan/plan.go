@@ -0,0 +1,160 @@
+// Package plan describes the ordered actions a command intends to perform
+// against the Docker daemon and the host, so they can be reviewed (as text
+// or JSON) before anything is changed, and then executed in sequence.
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	aur "github.com/logrusorgru/aurora"
+
+	"github.com/pygmystack/pygmy/internal/utils/color"
+)
+
+// Kind identifies the type of change an Action will make.
+type Kind string
+
+const (
+	PullImage        Kind = "pull-image"
+	TagImage         Kind = "tag-image"
+	CreateVolume     Kind = "create-volume"
+	RemoveVolume     Kind = "remove-volume"
+	CreateContainer  Kind = "create-container"
+	StartContainer   Kind = "start-container"
+	StopContainer    Kind = "stop-container"
+	KillContainer    Kind = "kill-container"
+	RemoveContainer  Kind = "remove-container"
+	RestartContainer Kind = "restart-container"
+	CreateNetwork    Kind = "create-network"
+	ConnectNetwork   Kind = "connect-network"
+	RemoveNetwork    Kind = "remove-network"
+	WriteResolver    Kind = "write-resolver"
+	RemoveResolver   Kind = "remove-resolver"
+	AddKey           Kind = "add-key"
+	StartForward     Kind = "start-forward"
+	StopForward      Kind = "stop-forward"
+)
+
+// descriptions are the human-readable verbs for each Kind.
+var descriptions = map[Kind]string{
+	PullImage:        "pull image",
+	TagImage:         "tag image",
+	CreateVolume:     "create volume",
+	RemoveVolume:     "remove volume",
+	CreateContainer:  "create container",
+	StartContainer:   "start container",
+	StopContainer:    "stop container",
+	KillContainer:    "kill container",
+	RemoveContainer:  "remove container",
+	RestartContainer: "restart container",
+	CreateNetwork:    "create network",
+	ConnectNetwork:   "connect container",
+	RemoveNetwork:    "remove network",
+	WriteResolver:    "write resolver file",
+	RemoveResolver:   "remove resolver file",
+	AddKey:           "add SSH key",
+	StartForward:     "forward ports to",
+	StopForward:      "stop forwarding ports to",
+}
+
+// Action is a single change which a command will make.
+type Action struct {
+	Kind   Kind   `json:"kind"`
+	Target string `json:"target"`
+	Detail string `json:"detail,omitempty"`
+	Sudo   bool   `json:"sudo"`
+
+	apply func() error
+}
+
+// New will return an Action which runs apply when the plan is executed.
+func New(kind Kind, target string, detail string, apply func() error) Action {
+	return Action{
+		Kind:   kind,
+		Target: target,
+		Detail: detail,
+		apply:  apply,
+	}
+}
+
+// WithSudo will flag the Action as requiring elevated privileges.
+func (a Action) WithSudo() Action {
+	a.Sudo = true
+	return a
+}
+
+// String will describe the action in a human-readable format.
+func (a Action) String() string {
+	verb, ok := descriptions[a.Kind]
+	if !ok {
+		verb = string(a.Kind)
+	}
+	s := fmt.Sprintf("%s %s", verb, a.Target)
+	if a.Detail != "" {
+		s = fmt.Sprintf("%s (%s)", s, a.Detail)
+	}
+	if a.Sudo {
+		s += " [sudo]"
+	}
+	return s
+}
+
+// Plan is the ordered list of actions for a given command.
+type Plan struct {
+	Command  string   `json:"command"`
+	Actions  []Action `json:"actions"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Add will append actions to the plan.
+func (p *Plan) Add(actions ...Action) {
+	p.Actions = append(p.Actions, actions...)
+}
+
+// Warn will append a warning to be shown alongside the plan.
+func (p *Plan) Warn(format string, a ...interface{}) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, a...))
+}
+
+// Print will write the plan in a human-readable format.
+func (p Plan) Print(w io.Writer) {
+	for _, warning := range p.Warnings {
+		_, _ = fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+	if len(p.Actions) == 0 {
+		_, _ = fmt.Fprintf(w, "Nothing to do for `%s`.\n", p.Command)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Pygmy would perform the following actions for `%s`:\n", p.Command)
+	for n, action := range p.Actions {
+		_, _ = fmt.Fprintf(w, "  %d. %s\n", n+1, action)
+	}
+}
+
+// JSON will return the plan marshalled to JSON.
+func (p Plan) JSON() ([]byte, error) {
+	if p.Actions == nil {
+		p.Actions = []Action{}
+	}
+	return json.Marshal(p)
+}
+
+// Execute will apply each action in order. A failed action is reported
+// and does not prevent the remaining actions from running, matching the
+// behaviour pygmy has always had. All failures are returned together.
+func (p Plan) Execute() error {
+	var errs []error
+	for _, action := range p.Actions {
+		if action.apply == nil {
+			continue
+		}
+		if err := action.apply(); err != nil {
+			color.Print(aur.Red(fmt.Sprintf("Failed to %s: %v\n", action.String(), err)))
+			errs = append(errs, fmt.Errorf("%s: %w", action.String(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,67 @@
+package plan_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/pygmystack/pygmy/internal/utils/plan"
+)
+
+func Example() {
+	p := plan.Plan{Command: "up"}
+	p.Add(plan.New(plan.CreateNetwork, "amazeeio-network", "", func() error { return nil }))
+	_ = p.Execute()
+}
+
+func Test(t *testing.T) {
+	Convey("Plan: Output tests...", t, func() {
+		p := plan.Plan{Command: "clean"}
+		p.Add(
+			plan.New(plan.RemoveContainer, "amazeeio-haproxy", "", nil),
+			plan.New(plan.RemoveResolver, "/etc/resolver/docker.amazee.io", "MacOS Resolver", nil).WithSudo(),
+		)
+
+		buf := new(bytes.Buffer)
+		p.Print(buf)
+		So(buf.String(), ShouldEqual, "Pygmy would perform the following actions for `clean`:\n"+
+			"  1. remove container amazeeio-haproxy\n"+
+			"  2. remove resolver file /etc/resolver/docker.amazee.io (MacOS Resolver) [sudo]\n")
+
+		data, err := p.JSON()
+		So(err, ShouldBeNil)
+		var decoded map[string]interface{}
+		So(json.Unmarshal(data, &decoded), ShouldBeNil)
+		So(decoded["command"], ShouldEqual, "clean")
+		So(decoded["actions"], ShouldHaveLength, 2)
+		So(decoded["actions"].([]interface{})[1].(map[string]interface{})["sudo"], ShouldBeTrue)
+	})
+
+	Convey("Plan: Empty plan tests...", t, func() {
+		p := plan.Plan{Command: "down"}
+		buf := new(bytes.Buffer)
+		p.Print(buf)
+		So(buf.String(), ShouldEqual, "Nothing to do for `down`.\n")
+
+		data, err := p.JSON()
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, `{"command":"down","actions":[]}`)
+	})
+
+	Convey("Plan: Execution tests...", t, func() {
+		var order []string
+		p := plan.Plan{Command: "up"}
+		p.Add(
+			plan.New(plan.PullImage, "a", "", func() error { order = append(order, "a"); return nil }),
+			plan.New(plan.CreateContainer, "b", "", func() error { order = append(order, "b"); return errors.New("failed") }),
+			plan.New(plan.StartContainer, "c", "", func() error { order = append(order, "c"); return nil }),
+		)
+		err := p.Execute()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "create container b")
+		So(order, ShouldResemble, []string{"a", "b", "c"})
+	})
+}
@@ -1,5 +1,10 @@
 package resolv
 
+import (
+	"fmt"
+	"os"
+)
+
 // Resolv is a struct of properties which are translates to a local resolv for
 // dnsmasq to redirect a given domain suffix to the local docker daemon.
 // Windows has a custom solution, however this will be used on both Mac
@@ -11,3 +16,8 @@ type Resolv struct {
 	Folder  string `yaml:"folder"`
 	Name    string `yaml:"name"`
 }
+
+// Path will return the full path to the resolver file.
+func (resolv Resolv) Path() string {
+	return fmt.Sprintf("%v%v%v", resolv.Folder, string(os.PathSeparator), resolv.File)
+}
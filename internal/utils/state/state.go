@@ -0,0 +1,162 @@
+// Package state persists values which pygmy decides at runtime, such as
+// the identifier used to label the resources it creates, so that they
+// remain stable between invocations.
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// State is the content of the state file.
+type State struct {
+	// InstanceID identifies the resources created by this installation.
+	InstanceID string `json:"instance_id"`
+
+	// Subnets are the subnets selected for each network, by name.
+	Subnets map[string]string `json:"subnets,omitempty"`
+
+	// Ports are the host ports remapped for each service, from the
+	// configured port to the port used instead.
+	Ports map[string]map[string]string `json:"ports,omitempty"`
+
+	// Keys are the SSH keys added to the agent, by their SHA256
+	// fingerprint.
+	Keys map[string]Key `json:"keys,omitempty"`
+
+	// Images are the images pulled by update for each service, by
+	// container name.
+	Images map[string]Image `json:"images,omitempty"`
+}
+
+// Key is an SSH key added to the agent.
+type Key struct {
+	// Path is the key which was added.
+	Path string `json:"path"`
+
+	// Expires is when the agent will remove the key, if it was added with
+	// a lifetime.
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// UnmarshalJSON will read a key, which state files written by earlier
+// releases record as the path alone.
+func (k *Key) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*k = Key{Path: path}
+		return nil
+	}
+	type key Key
+	return json.Unmarshal(data, (*key)(k))
+}
+
+// Image is the image a service was updated to.
+type Image struct {
+	// Reference is the configured image.
+	Reference string `json:"reference"`
+
+	// Digest is the digest of the image in use.
+	Digest string `json:"digest"`
+
+	// Previous is the digest which was in use before the last update or
+	// rollback, which a rollback returns to.
+	Previous string `json:"previous,omitempty"`
+
+	// Updated is when the digest last changed.
+	Updated time.Time `json:"updated,omitzero"`
+}
+
+// Path will return the location of the state file. It can be overridden
+// with the PYGMY_STATE environment variable.
+func Path() string {
+	if p := os.Getenv("PYGMY_STATE"); p != "" {
+		return p
+	}
+	home, _ := homedir.Dir()
+	return filepath.Join(home, ".pygmy", "state.json")
+}
+
+// Load will read the state file. A missing state file is not an error and
+// will return an empty State.
+func Load() (State, error) {
+	s := State{}
+	data, err := os.ReadFile(Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return State{}, fmt.Errorf("could not read state file %s: %w", Path(), err)
+	}
+	return s, nil
+}
+
+// Save will write the state file, replacing the previous content.
+func (s State) Save() error {
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted write can't
+	// leave a truncated state file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Update will load the state, apply fn and save the result.
+func Update(fn func(s *State)) error {
+	s, err := Load()
+	if err != nil {
+		return err
+	}
+	fn(&s)
+	return s.Save()
+}
+
+// InstanceID will return the identifier for this installation, creating
+// and persisting a new one if none exists.
+func InstanceID() (string, error) {
+	s, err := Load()
+	if err != nil {
+		return "", err
+	}
+	if s.InstanceID != "" {
+		return s.InstanceID, nil
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	if err := Update(func(s *State) { s.InstanceID = id }); err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,51 @@
+package state_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/pygmystack/pygmy/internal/utils/state"
+)
+
+func Test(t *testing.T) {
+	t.Setenv("PYGMY_STATE", filepath.Join(t.TempDir(), "nested", "state.json"))
+
+	Convey("State: Missing file tests...", t, func() {
+		s, err := state.Load()
+		So(err, ShouldBeNil)
+		So(s.InstanceID, ShouldBeEmpty)
+	})
+
+	Convey("State: Instance ID tests...", t, func() {
+		first, err := state.InstanceID()
+		So(err, ShouldBeNil)
+		So(first, ShouldHaveLength, 16)
+
+		second, err := state.InstanceID()
+		So(err, ShouldBeNil)
+		So(second, ShouldEqual, first)
+
+		info, err := os.Stat(state.Path())
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+	})
+
+	Convey("State: Key migration tests...", t, func() {
+		So(os.WriteFile(state.Path(), []byte(`{"instance_id":"0123456789abcdef","keys":{"SHA256:old":"/home/user/.ssh/id_rsa","SHA256:new":{"path":"/home/user/.ssh/id_ed25519","expires":"2030-01-01T00:00:00Z"}}}`), 0600), ShouldBeNil)
+		s, err := state.Load()
+		So(err, ShouldBeNil)
+		So(s.InstanceID, ShouldEqual, "0123456789abcdef")
+		So(s.Keys["SHA256:old"], ShouldResemble, state.Key{Path: "/home/user/.ssh/id_rsa"})
+		So(s.Keys["SHA256:new"].Path, ShouldEqual, "/home/user/.ssh/id_ed25519")
+		So(s.Keys["SHA256:new"].Expires.Year(), ShouldEqual, 2030)
+	})
+
+	Convey("State: Corrupt file tests...", t, func() {
+		So(os.WriteFile(state.Path(), []byte("{"), 0600), ShouldBeNil)
+		_, err := state.Load()
+		So(err, ShouldNotBeNil)
+	})
+}